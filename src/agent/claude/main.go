@@ -3,14 +3,42 @@ package main
 import (
 	"bufio"
 	"context"
+	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"os"
 
-	"github.com/anthropics/anthropic-sdk-go"
+	"agent/rankee"
+	"agent/tool"
+
+	"github.com/jacygao/ai/llm"
+	"github.com/jacygao/ai/llm/provider"
 )
 
 func main() {
-	client := anthropic.NewClient()
+	var cfg provider.Config
+	cfg.RegisterFlags(flag.CommandLine, provider.Anthropic)
+	maxSteps := flag.Int("max-steps", defaultMaxSteps, "Maximum number of model calls per user message")
+	flag.Parse()
+
+	client, err := provider.New(cfg)
+	if err != nil {
+		fmt.Printf("Error: %s\n", err.Error())
+		return
+	}
+
+	rankeeHost := os.Getenv("RANKEE_HOST")
+	if rankeeHost == "" {
+		fmt.Println("Please set the RANKEE_HOST environment variable.")
+		return
+	}
+
+	tools, err := tool.NewDefaultRegistry(rankee.NewClient(rankeeHost))
+	if err != nil {
+		fmt.Printf("Error: %s\n", err.Error())
+		return
+	}
 
 	scanner := bufio.NewScanner(os.Stdin)
 	getUserMessage := func() (string, bool) {
@@ -20,27 +48,44 @@ func main() {
 		return scanner.Text(), true
 	}
 
-	agent := NewAgent(&client, getUserMessage)
-	err := agent.Run(context.TODO())
+	agent := NewAgent(client, getUserMessage, tools, *maxSteps)
+	err = agent.Run(context.TODO())
 	if err != nil {
 		fmt.Printf("Error: %s\n", err.Error())
 	}
 }
 
-func NewAgent(client *anthropic.Client, getUserMessage func() (string, bool)) *Agent {
+var (
+	// ErrMaxSteps is returned when Claude keeps asking for tools beyond the
+	// configured maximum number of steps in a single turn.
+	ErrMaxSteps = errors.New("maximum number of steps reached")
+	// ErrMaxTokens is returned when a reply is cut off by the token limit.
+	ErrMaxTokens = errors.New("reply cut off at the maximum number of tokens")
+)
+
+const defaultMaxSteps = 10
+
+func NewAgent(client llm.Chatter, getUserMessage func() (string, bool), tools *tool.Registry, maxSteps int) *Agent {
+	if maxSteps <= 0 {
+		maxSteps = defaultMaxSteps
+	}
 	return &Agent{
 		client:         client,
 		getUserMessage: getUserMessage,
+		tools:          tools,
+		maxSteps:       maxSteps,
 	}
 }
 
 type Agent struct {
-	client         *anthropic.Client
+	client         llm.Chatter
 	getUserMessage func() (string, bool)
+	tools          *tool.Registry
+	maxSteps       int
 }
 
 func (a *Agent) Run(ctx context.Context) error {
-	conversation := []anthropic.MessageParam{}
+	conversation := []llm.Message{}
 
 	fmt.Println("Chat with Claude (use 'ctrl-c' to quit)")
 
@@ -51,31 +96,63 @@ func (a *Agent) Run(ctx context.Context) error {
 			break
 		}
 
-		userMessage := anthropic.NewUserMessage(anthropic.NewTextBlock(userInput))
-		conversation = append(conversation, userMessage)
-
-		message, err := a.runInference(ctx, conversation)
+		conversation = append(conversation, llm.UserMessage(userInput))
+		var err error
+		conversation, err = a.runTurn(ctx, conversation)
 		if err != nil {
 			return err
 		}
-		conversation = append(conversation, message.ToParam())
+	}
+
+	return nil
+}
+
+// runTurn keeps sending tool results back until Claude ends its turn, or
+// until maxSteps inferences have been made.
+func (a *Agent) runTurn(ctx context.Context, conversation []llm.Message) ([]llm.Message, error) {
+	for step := 0; step < a.maxSteps; step++ {
+		response, err := a.runInference(ctx, conversation)
+		if err != nil {
+			return conversation, err
+		}
+		conversation = append(conversation, response.Message)
+
+		if response.Message.Content != "" {
+			fmt.Printf("\u001b[93mClaude\u001b[0m: %s\n", response.Message.Content)
+		}
 
-		for _, content := range message.Content {
-			switch content.Type {
-			case "text":
-				fmt.Printf("\u001b[93mClaude\u001b[0m: %s\n", content.Text)
+		switch response.StopReason {
+		case llm.StopMaxTokens:
+			return conversation, ErrMaxTokens
+		case llm.StopToolUse:
+			if len(response.Message.ToolCalls) == 0 {
+				return conversation, nil
 			}
+		default:
+			return conversation, nil
+		}
+
+		for _, call := range response.Message.ToolCalls {
+			conversation = append(conversation, a.executeTool(ctx, call.ID, call.Name, call.Arguments))
 		}
 	}
 
-	return nil
+	return conversation, fmt.Errorf("%w: no final answer after %d steps", ErrMaxSteps, a.maxSteps)
+}
+
+func (a *Agent) executeTool(ctx context.Context, id, name string, input json.RawMessage) llm.Message {
+	fmt.Printf("\u001b[92mtool\u001b[0m: %s(%s)\n", name, input)
+	response, err := a.tools.Call(ctx, name, input)
+	if err != nil {
+		return llm.ToolMessage(id, err.Error(), true)
+	}
+	return llm.ToolMessage(id, response, false)
 }
 
-func (a *Agent) runInference(ctx context.Context, conversation []anthropic.MessageParam) (*anthropic.Message, error) {
-	message, err := a.client.Messages.New(ctx, anthropic.MessageNewParams{
-		Model:     anthropic.ModelClaude3_7SonnetLatest,
-		MaxTokens: int64(1024),
+func (a *Agent) runInference(ctx context.Context, conversation []llm.Message) (*llm.Response, error) {
+	return a.client.Chat(ctx, llm.Request{
+		MaxTokens: 1024,
 		Messages:  conversation,
+		Tools:     a.tools.Definitions(),
 	})
-	return message, err
 }
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"agent/rankee"
+	"agent/rankee/rankeetest"
+	"agent/tool"
+
+	"github.com/jacygao/ai/llm"
+	"github.com/jacygao/ai/llm/llmtest"
+)
+
+func newTestAgent(t *testing.T, fake *llmtest.Fake, maxSteps int) *Agent {
+	t.Helper()
+	server := rankeetest.NewServer(rankeetest.NewFake())
+	t.Cleanup(server.Close)
+	client := rankee.NewClient(server.URL)
+	client.PollInterval = time.Millisecond
+
+	tools, err := tool.NewDefaultRegistry(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewAgent(fake, nil, tools, maxSteps)
+}
+
+func call(id, name, arguments string) llm.ToolCall {
+	return llm.ToolCall{ID: id, Name: name, Arguments: json.RawMessage(arguments)}
+}
+
+func TestRunTurnCallsTools(t *testing.T) {
+	fake := &llmtest.Fake{Responses: []*llm.Response{
+		llmtest.CallTools(
+			call("toolu_1", "get_weather", `{"location":"Sydney"}`),
+			call("toolu_2", "get_weather", `{}`),
+			call("toolu_3", "get_time", `{}`),
+		),
+		llmtest.Reply("It is sunny in Sydney."),
+	}}
+	agent := newTestAgent(t, fake, 0)
+
+	conversation, err := agent.runTurn(context.Background(), []llm.Message{llm.UserMessage("Weather in Sydney?")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := len(conversation); got != 6 {
+		t.Errorf("got %d messages, want user, tool calls, three results and answer", got)
+	}
+
+	want := []llm.Message{
+		llm.ToolMessage("toolu_1", "Sunny, 25°C", false),
+		llm.ToolMessage("toolu_2", `missing required field "location"`, true),
+		llm.ToolMessage("toolu_3", `unknown tool "get_time"`, true),
+	}
+	messages := fake.Requests()[1].Messages
+	for i, result := range messages[len(messages)-3:] {
+		if result.ToolCallID != want[i].ToolCallID || !strings.Contains(result.Content, want[i].Content) || result.IsError != want[i].IsError {
+			t.Errorf("got tool result %+v, want %+v", result, want[i])
+		}
+	}
+}
+
+func TestRunTurnRankeeEvaluation(t *testing.T) {
+	fake := &llmtest.Fake{Responses: []*llm.Response{
+		llmtest.CallTools(call("toolu_1", "run_rankee_evaluation", `{"AppId":"app-1","Index":"products"}`)),
+		llmtest.Reply("The overall score is 0.82."),
+	}}
+	agent := newTestAgent(t, fake, 0)
+
+	if _, err := agent.runTurn(context.Background(), []llm.Message{llm.UserMessage("Evaluate products of app-1")}); err != nil {
+		t.Fatal(err)
+	}
+
+	messages := fake.Requests()[1].Messages
+	result := messages[len(messages)-1]
+	var results rankee.EvaluationResults
+	if err := json.Unmarshal([]byte(result.Content), &results); err != nil {
+		t.Fatalf("tool result %q is not evaluation results: %v", result.Content, err)
+	}
+	if result.IsError || results.OverallScore != 0.82 {
+		t.Errorf("got tool result %+v", result)
+	}
+}
+
+func TestRunTurnStopReasons(t *testing.T) {
+	tests := []struct {
+		name     string
+		response *llm.Response
+		err      error
+	}{
+		{
+			name:     "end turn",
+			response: llmtest.Reply("Done."),
+		},
+		{
+			name:     "max tokens",
+			response: &llm.Response{Message: llm.AssistantMessage("It is sun"), StopReason: llm.StopMaxTokens},
+			err:      ErrMaxTokens,
+		},
+		{
+			name: "end turn with tool calls",
+			response: &llm.Response{
+				Message:    llm.Message{Role: llm.RoleAssistant, ToolCalls: []llm.ToolCall{call("toolu_1", "get_weather", `{"location":"Sydney"}`)}},
+				StopReason: llm.StopEnd,
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fake := &llmtest.Fake{Responses: []*llm.Response{test.response}}
+			agent := newTestAgent(t, fake, 0)
+
+			_, err := agent.runTurn(context.Background(), []llm.Message{llm.UserMessage("Weather?")})
+			if !errors.Is(err, test.err) {
+				t.Errorf("got error %v, want %v", err, test.err)
+			}
+			if got := len(fake.Requests()); got != 1 {
+				t.Errorf("got %d model calls, want 1", got)
+			}
+		})
+	}
+}
+
+func TestRunTurnMaxSteps(t *testing.T) {
+	weather := llmtest.CallTools(call("toolu_1", "get_weather", `{"location":"Sydney"}`))
+	fake := &llmtest.Fake{Responses: []*llm.Response{weather, weather, weather}}
+	agent := newTestAgent(t, fake, 2)
+
+	if _, err := agent.runTurn(context.Background(), []llm.Message{llm.UserMessage("Weather?")}); !errors.Is(err, ErrMaxSteps) {
+		t.Errorf("got error %v, want %v", err, ErrMaxSteps)
+	}
+	if got := len(fake.Requests()); got != 2 {
+		t.Errorf("got %d model calls, want 2", got)
+	}
+}
+
+func TestRunKeepsHistory(t *testing.T) {
+	fake := llmtest.NewFake("Hello!", "You said hi.")
+	agent := newTestAgent(t, fake, 0)
+	inputs := []string{"hi", "what did I say?"}
+	agent.getUserMessage = func() (string, bool) {
+		if len(inputs) == 0 {
+			return "", false
+		}
+		input := inputs[0]
+		inputs = inputs[1:]
+		return input, true
+	}
+
+	if err := agent.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	requests := fake.Requests()
+	if len(requests) != 2 {
+		t.Fatalf("got %d requests, want 2", len(requests))
+	}
+	if got := len(requests[1].Messages); got != 3 {
+		t.Errorf("got %d messages in the second request, want the first exchange and the new question", got)
+	}
+	if len(requests[0].Tools) != 2 {
+		t.Errorf("got %d tools, want 2", len(requests[0].Tools))
+	}
+}
@@ -2,12 +2,15 @@ module agent
 
 go 1.24.2
 
-require github.com/anthropics/anthropic-sdk-go v1.4.0
+require github.com/jacygao/ai v0.0.0
 
 require (
+	github.com/anthropics/anthropic-sdk-go v1.4.0 // indirect
 	github.com/openai/openai-go v1.5.0 // indirect
 	github.com/tidwall/gjson v1.14.4 // indirect
 	github.com/tidwall/match v1.1.1 // indirect
 	github.com/tidwall/pretty v1.2.1 // indirect
 	github.com/tidwall/sjson v1.2.5 // indirect
 )
+
+replace github.com/jacygao/ai => ../embedding/go
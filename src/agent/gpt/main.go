@@ -3,29 +3,47 @@ package main
 import (
 	"bufio"
 	"context"
-	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"os"
+	"sync"
 
-	"github.com/openai/openai-go"
-	"github.com/openai/openai-go/option"
+	"agent/rankee"
+	"agent/tool"
+
+	"github.com/jacygao/ai/llm"
+	"github.com/jacygao/ai/llm/provider"
 )
 
 func main() {
-	apiKey := os.Getenv("OPENAI_API_KEY")
-	if apiKey == "" {
-		fmt.Println("Please set the OPENAI_API_KEY environment variable.")
+	var cfg provider.Config
+	cfg.RegisterFlags(flag.CommandLine, provider.OpenAI)
+	maxSteps := flag.Int("max-steps", defaultMaxSteps, "Maximum number of model calls per user message")
+	flag.Parse()
+
+	if cfg.Name == provider.OpenAI {
+		cfg.APIKey = os.Getenv("OPENAI_API_KEY")
+		if cfg.APIKey == "" {
+			fmt.Println("Please set the OPENAI_API_KEY environment variable.")
+			return
+		}
+	}
+	client, err := provider.New(cfg)
+	if err != nil {
+		fmt.Printf("Error: %s\n", err.Error())
 		return
 	}
-	client := openai.NewClient(option.WithAPIKey(apiKey))
 
 	rankeeHost := os.Getenv("RANKEE_HOST")
 	if rankeeHost == "" {
 		fmt.Println("Please set the RANKEE_HOST environment variable.")
 		return
 	}
-	tools := &Tools{
-		RankeeClient: NewRankeeClient(rankeeHost),
+	tools, err := tool.NewDefaultRegistry(rankee.NewClient(rankeeHost))
+	if err != nil {
+		fmt.Printf("Error: %s\n", err.Error())
+		return
 	}
 
 	scanner := bufio.NewScanner(os.Stdin)
@@ -36,71 +54,48 @@ func main() {
 		return scanner.Text(), true
 	}
 
-	agent := NewAgent(&client, tools, getUserMessage)
-	err := agent.Run(context.TODO())
+	agent := NewAgent(client, tools, getUserMessage, *maxSteps)
+	err = agent.Run(context.TODO())
 	if err != nil {
 		fmt.Printf("Error: %s\n", err.Error())
 	}
 }
 
-type Tools struct {
-	RankeeClient *RankeeClient
-}
+var (
+	// ErrMaxSteps is returned when the model keeps asking for tools beyond the
+	// configured maximum number of steps in a single turn.
+	ErrMaxSteps = errors.New("maximum number of steps reached")
+	// ErrMaxTokens is returned when a reply is cut off by the token limit.
+	ErrMaxTokens = errors.New("reply cut off at the maximum number of tokens")
+)
 
-func NewAgent(client *openai.Client, tools *Tools, getUserMessage func() (string, bool)) *Agent {
+const defaultMaxSteps = 10
+
+func NewAgent(client llm.Chatter, tools *tool.Registry, getUserMessage func() (string, bool), maxSteps int) *Agent {
+	if maxSteps <= 0 {
+		maxSteps = defaultMaxSteps
+	}
 	return &Agent{
 		client:         client,
 		getUserMessage: getUserMessage,
 		tools:          tools,
+		maxSteps:       maxSteps,
 	}
 }
 
 type Agent struct {
-	client         *openai.Client
+	client         llm.Chatter
 	getUserMessage func() (string, bool)
-	tools          *Tools
+	tools          *tool.Registry
+	maxSteps       int
 }
 
 func (a *Agent) Run(ctx context.Context) error {
-	params := openai.ChatCompletionNewParams{
-		Messages: []openai.ChatCompletionMessageParamUnion{},
-		Seed:     openai.Int(0),
-		Model:    openai.ChatModelGPT4o,
-		Tools: []openai.ChatCompletionToolParam{
-			{
-				Function: openai.FunctionDefinitionParam{
-					Name:        "get_weather",
-					Description: openai.String("Get weather at the given location"),
-					Parameters: openai.FunctionParameters{
-						"type": "object",
-						"properties": map[string]interface{}{
-							"location": map[string]string{
-								"type": "string",
-							},
-						},
-						"required": []string{"location"},
-					},
-				},
-			},
-			{
-				Function: openai.FunctionDefinitionParam{
-					Name:        "run_rankee_evaluation",
-					Description: openai.String("Run a Rankee evaluation with given parameters"),
-					Parameters: openai.FunctionParameters{
-						"type": "object",
-						"properties": map[string]interface{}{
-							"AppId": map[string]string{
-								"type": "string",
-							},
-							"Index": map[string]string{
-								"type": "string",
-							},
-						},
-						"required": []string{"AppId", "Index"},
-					},
-				},
-			},
-		},
+	seed := int64(0)
+	req := llm.Request{
+		Messages: []llm.Message{},
+		Seed:     &seed,
+		Tools:    a.tools.Definitions(),
 	}
 
 	fmt.Println("Chat with GPT (use 'ctrl-c' to quit)")
@@ -112,73 +107,65 @@ func (a *Agent) Run(ctx context.Context) error {
 			break
 		}
 
-		params.Messages = append(params.Messages, openai.UserMessage(userInput))
-		completion, err := a.client.Chat.Completions.New(ctx, params)
+		req.Messages = append(req.Messages, llm.UserMessage(userInput))
+		content, err := a.runTurn(ctx, &req)
 		if err != nil {
-			return fmt.Errorf("error generating completion: %w", err)
+			return err
 		}
+		fmt.Printf("\u001b[93mGPT\u001b[0m: %s\n", content)
+	}
 
-		toolCalls := completion.Choices[0].Message.ToolCalls
-
-		// Return early if there are no tool calls
-		if len(toolCalls) > 0 {
-			params.Messages = append(params.Messages, completion.Choices[0].Message.ToParam())
-			for _, toolCall := range toolCalls {
-				if toolCall.Function.Name == "get_weather" {
-					// Extract the location from the function call arguments
-					var args map[string]interface{}
-					err := json.Unmarshal([]byte(toolCall.Function.Arguments), &args)
-					if err != nil {
-						panic(err)
-					}
-					location := args["location"].(string)
-
-					// Simulate getting weather data
-					weatherData := getWeather(location)
-
-					// Print the weather data
-					fmt.Printf("Getting the weather for %s: %s\n", location, weatherData)
-
-					params.Messages = append(params.Messages, openai.ToolMessage(weatherData, toolCall.ID))
-				}
-				if toolCall.Function.Name == "run_rankee_evaluation" {
-					// Extract the location from the function call arguments
-					var args map[string]interface{}
-					err := json.Unmarshal([]byte(toolCall.Function.Arguments), &args)
-					if err != nil {
-						panic(err)
-					}
-					AppId := args["AppId"].(string)
-					Index := args["Index"].(string)
-					// Simulate getting weather data
-					result := runRankeeEvaluation(AppId, Index)
-
-					params.Messages = append(params.Messages, openai.ToolMessage(result, toolCall.ID))
-				}
-			}
-			completion, err = a.client.Chat.Completions.New(ctx, params)
-			if err != nil {
-				return fmt.Errorf("error generating completion after tool call: %w", err)
-			}
+	return nil
+}
+
+// runTurn keeps calling the model and running the tools it asks for until it
+// stops asking for tools, or until maxSteps completions have been made.
+func (a *Agent) runTurn(ctx context.Context, req *llm.Request) (string, error) {
+	for step := 0; step < a.maxSteps; step++ {
+		response, err := a.client.Chat(ctx, *req)
+		if err != nil {
+			return "", fmt.Errorf("error generating completion: %w", err)
+		}
+		req.Messages = append(req.Messages, response.Message)
+
+		toolCalls := response.Message.ToolCalls
+		if response.StopReason == llm.StopMaxTokens {
+			return "", ErrMaxTokens
+		}
+		if response.StopReason != llm.StopToolUse || len(toolCalls) == 0 {
+			return response.Message.Content, nil
 		}
 
-		params.Messages = append(params.Messages, completion.Choices[0].Message.ToParam())
-		fmt.Printf("\u001b[93mGPT\u001b[0m: %s\n", completion.Choices[0].Message.Content)
+		req.Messages = append(req.Messages, a.runToolCalls(ctx, toolCalls)...)
 	}
 
-	return nil
+	return "", fmt.Errorf("%w: no final answer after %d steps", ErrMaxSteps, a.maxSteps)
 }
 
-// Mock function to simulate weather data retrieval
-func getWeather(location string) string {
-	// In a real implementation, this function would call a weather API
-	return "Sunny, 25°C"
+// runToolCalls runs every tool call of a single turn concurrently and returns
+// their results in the same order as toolCalls. Failed calls are flagged as
+// errors to the model.
+func (a *Agent) runToolCalls(ctx context.Context, toolCalls []llm.ToolCall) []llm.Message {
+	results := make([]llm.Message, len(toolCalls))
+
+	var wg sync.WaitGroup
+	for i, toolCall := range toolCalls {
+		wg.Add(1)
+		go func(i int, toolCall llm.ToolCall) {
+			defer wg.Done()
+			results[i] = a.runToolCall(ctx, toolCall)
+		}(i, toolCall)
+	}
+	wg.Wait()
+
+	return results
 }
 
-func runRankeeEvaluation(appID string, index string) string {
-	// This function would contain the logic to run the Rankee evaluation
-	// using the Client struct and its methods as described in the prompt.
-	// For now, it's left empty as a placeholder.
-	fmt.Println("Running Rankee evaluation...")
-	return "running"
+func (a *Agent) runToolCall(ctx context.Context, toolCall llm.ToolCall) llm.Message {
+	fmt.Printf("\u001b[92mtool\u001b[0m: %s(%s)\n", toolCall.Name, toolCall.Arguments)
+	result, err := a.tools.Call(ctx, toolCall.Name, toolCall.Arguments)
+	if err != nil {
+		return llm.ToolMessage(toolCall.ID, err.Error(), true)
+	}
+	return llm.ToolMessage(toolCall.ID, result, false)
 }
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"agent/rankee"
+	"agent/rankee/rankeetest"
+	"agent/tool"
+
+	"github.com/jacygao/ai/llm"
+	"github.com/jacygao/ai/llm/llmtest"
+)
+
+func newTestAgent(t *testing.T, fake *llmtest.Fake, maxSteps int) *Agent {
+	t.Helper()
+	server := rankeetest.NewServer(rankeetest.NewFake())
+	t.Cleanup(server.Close)
+	client := rankee.NewClient(server.URL)
+	client.PollInterval = time.Millisecond
+
+	tools, err := tool.NewDefaultRegistry(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewAgent(fake, tools, nil, maxSteps)
+}
+
+func call(id, name, arguments string) llm.ToolCall {
+	return llm.ToolCall{ID: id, Name: name, Arguments: json.RawMessage(arguments)}
+}
+
+// lastToolMessage returns the tool result the nth request sent to the model.
+func lastToolMessage(t *testing.T, fake *llmtest.Fake, n int) llm.Message {
+	t.Helper()
+	requests := fake.Requests()
+	if len(requests) <= n {
+		t.Fatalf("got %d requests, want more than %d", len(requests), n)
+	}
+	messages := requests[n].Messages
+	last := messages[len(messages)-1]
+	if last.Role != llm.RoleTool {
+		t.Fatalf("request %d ends with a %s message, want a tool result", n, last.Role)
+	}
+	return last
+}
+
+func TestRunTurnCallsTools(t *testing.T) {
+	fake := &llmtest.Fake{Responses: []*llm.Response{
+		llmtest.CallTools(call("call_1", "get_weather", `{"location":"Sydney"}`)),
+		llmtest.Reply("It is sunny in Sydney."),
+	}}
+	agent := newTestAgent(t, fake, 0)
+
+	req := &llm.Request{Messages: []llm.Message{llm.UserMessage("Weather in Sydney?")}, Tools: agent.tools.Definitions()}
+	content, err := agent.runTurn(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content != "It is sunny in Sydney." {
+		t.Errorf("got %q", content)
+	}
+
+	result := lastToolMessage(t, fake, 1)
+	if result.ToolCallID != "call_1" || result.Content != "Sunny, 25°C" || result.IsError {
+		t.Errorf("got tool result %+v", result)
+	}
+	if got := len(req.Messages); got != 4 {
+		t.Errorf("got %d messages in the conversation, want user, tool call, result and answer", got)
+	}
+}
+
+func TestRunTurnRankeeEvaluation(t *testing.T) {
+	fake := &llmtest.Fake{Responses: []*llm.Response{
+		llmtest.CallTools(call("call_1", "run_rankee_evaluation", `{"AppId":"app-1","Index":"products"}`)),
+		llmtest.Reply("The overall score is 0.82."),
+	}}
+	agent := newTestAgent(t, fake, 0)
+
+	req := &llm.Request{Messages: []llm.Message{llm.UserMessage("Evaluate products of app-1")}}
+	if _, err := agent.runTurn(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+
+	var results rankee.EvaluationResults
+	result := lastToolMessage(t, fake, 1)
+	if err := json.Unmarshal([]byte(result.Content), &results); err != nil {
+		t.Fatalf("tool result %q is not evaluation results: %v", result.Content, err)
+	}
+	if results.OverallScore != 0.82 || len(results.Queries) != 2 {
+		t.Errorf("got results %+v", results)
+	}
+}
+
+func TestRunTurnReportsToolErrors(t *testing.T) {
+	fake := &llmtest.Fake{Responses: []*llm.Response{
+		llmtest.CallTools(
+			call("call_1", "get_weather", `{}`),
+			call("call_2", "get_time", `{}`),
+		),
+		llmtest.Reply("Sorry."),
+	}}
+	agent := newTestAgent(t, fake, 0)
+
+	req := &llm.Request{Messages: []llm.Message{llm.UserMessage("Weather?")}}
+	if _, err := agent.runTurn(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+
+	messages := fake.Requests()[1].Messages
+	results := messages[len(messages)-2:]
+	if results[0].ToolCallID != "call_1" || !results[0].IsError || !strings.Contains(results[0].Content, `missing required field "location"`) {
+		t.Errorf("got %+v, want an error for the missing location", results[0])
+	}
+	if results[1].ToolCallID != "call_2" || !results[1].IsError || !strings.Contains(results[1].Content, `unknown tool "get_time"`) {
+		t.Errorf("got %+v, want an unknown tool error", results[1])
+	}
+}
+
+type waitArgs struct {
+	Delay int `json:"delay"`
+}
+
+func TestRunToolCallsConcurrently(t *testing.T) {
+	const calls = 3
+	var started sync.WaitGroup
+	started.Add(calls)
+	allStarted := make(chan struct{})
+	go func() {
+		started.Wait()
+		close(allStarted)
+	}()
+
+	// Every call blocks until all of them have started, so the calls only
+	// finish if they run at the same time. Later calls finish first.
+	tools := tool.NewRegistry()
+	err := tool.Register(tools, "wait", "Wait for the other calls", func(ctx context.Context, args waitArgs) (string, error) {
+		started.Done()
+		select {
+		case <-allStarted:
+		case <-time.After(5 * time.Second):
+			return "", errors.New("ran alone")
+		}
+		time.Sleep(time.Duration(args.Delay) * time.Millisecond)
+		return strconv.Itoa(args.Delay), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	agent := NewAgent(&llmtest.Fake{}, tools, nil, 0)
+
+	results := agent.runToolCalls(context.Background(), []llm.ToolCall{
+		call("call_1", "wait", `{"delay":30}`),
+		call("call_2", "wait", `{"delay":15}`),
+		call("call_3", "wait", `{"delay":0}`),
+	})
+
+	want := []llm.Message{
+		llm.ToolMessage("call_1", "30", false),
+		llm.ToolMessage("call_2", "15", false),
+		llm.ToolMessage("call_3", "0", false),
+	}
+	if !reflect.DeepEqual(results, want) {
+		t.Errorf("got %+v, want %+v", results, want)
+	}
+}
+
+func TestRunTurnMaxTokens(t *testing.T) {
+	fake := &llmtest.Fake{Responses: []*llm.Response{
+		{Message: llm.AssistantMessage("It is sun"), StopReason: llm.StopMaxTokens},
+	}}
+	agent := newTestAgent(t, fake, 0)
+
+	req := &llm.Request{Messages: []llm.Message{llm.UserMessage("Weather?")}}
+	content, err := agent.runTurn(context.Background(), req)
+	if !errors.Is(err, ErrMaxTokens) || content != "" {
+		t.Errorf("got %q, %v, want %v", content, err, ErrMaxTokens)
+	}
+}
+
+func TestRunTurnMaxSteps(t *testing.T) {
+	weather := llmtest.CallTools(call("call_1", "get_weather", `{"location":"Sydney"}`))
+	fake := &llmtest.Fake{Responses: []*llm.Response{weather, weather, weather}}
+	agent := newTestAgent(t, fake, 2)
+
+	req := &llm.Request{Messages: []llm.Message{llm.UserMessage("Weather?")}}
+	if _, err := agent.runTurn(context.Background(), req); !errors.Is(err, ErrMaxSteps) {
+		t.Errorf("got error %v, want %v", err, ErrMaxSteps)
+	}
+	if got := len(fake.Requests()); got != 2 {
+		t.Errorf("got %d model calls, want 2", got)
+	}
+}
+
+func TestRunKeepsHistory(t *testing.T) {
+	fake := llmtest.NewFake("Hello!", "You said hi.")
+	agent := newTestAgent(t, fake, 0)
+	inputs := []string{"hi", "what did I say?"}
+	agent.getUserMessage = func() (string, bool) {
+		if len(inputs) == 0 {
+			return "", false
+		}
+		input := inputs[0]
+		inputs = inputs[1:]
+		return input, true
+	}
+
+	if err := agent.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	requests := fake.Requests()
+	if len(requests) != 2 {
+		t.Fatalf("got %d requests, want 2", len(requests))
+	}
+	if got := len(requests[1].Messages); got != 3 {
+		t.Errorf("got %d messages in the second request, want the first exchange and the new question", got)
+	}
+	if requests[0].Seed == nil || len(requests[0].Tools) != 2 {
+		t.Errorf("got seed %v and %d tools, want a seed and both tools", requests[0].Seed, len(requests[0].Tools))
+	}
+}
@@ -0,0 +1,159 @@
+// Package rankee is a client for Rankee, the search relevance evaluation
+// service the agents run evaluations with.
+package rankee
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Evaluation statuses reported by Rankee.
+const (
+	EvaluationPending   = "pending"
+	EvaluationRunning   = "running"
+	EvaluationCompleted = "completed"
+	EvaluationFailed    = "failed"
+)
+
+// Client talks to the Rankee search relevance evaluation service.
+type Client struct {
+	host         string
+	httpClient   *http.Client
+	PollInterval time.Duration
+}
+
+func NewClient(host string) *Client {
+	return &Client{
+		host:         strings.TrimSuffix(host, "/"),
+		httpClient:   &http.Client{Timeout: 30 * time.Second},
+		PollInterval: 2 * time.Second,
+	}
+}
+
+type Evaluation struct {
+	ID      string             `json:"id"`
+	AppId   string             `json:"appId"`
+	Index   string             `json:"index"`
+	Status  string             `json:"status"`
+	Error   string             `json:"error,omitempty"`
+	Results *EvaluationResults `json:"results,omitempty"`
+}
+
+type EvaluationResults struct {
+	OverallScore float64        `json:"overallScore"`
+	Queries      []QueryMetrics `json:"queries"`
+}
+
+type QueryMetrics struct {
+	Query   string             `json:"query"`
+	Metrics map[string]float64 `json:"metrics"` // metric name (e.g. "ndcg@10") -> value
+}
+
+// StartEvaluationRequest is the body of POST /evaluations.
+type StartEvaluationRequest struct {
+	AppId string `json:"appId"`
+	Index string `json:"index"`
+}
+
+// StartEvaluation asks Rankee to evaluate the given index of an app and
+// returns the evaluation as soon as it has been accepted.
+func (c *Client) StartEvaluation(ctx context.Context, appID string, index string) (*Evaluation, error) {
+	body, err := json.Marshal(StartEvaluationRequest{AppId: appID, Index: index})
+	if err != nil {
+		return nil, fmt.Errorf("error encoding evaluation request: %w", err)
+	}
+
+	evaluation := &Evaluation{}
+	if err := c.do(ctx, http.MethodPost, "/evaluations", body, evaluation); err != nil {
+		return nil, fmt.Errorf("error starting evaluation: %w", err)
+	}
+	return evaluation, nil
+}
+
+// GetEvaluation returns the current status, and the results once completed,
+// of the evaluation with the given ID.
+func (c *Client) GetEvaluation(ctx context.Context, id string) (*Evaluation, error) {
+	evaluation := &Evaluation{}
+	if err := c.do(ctx, http.MethodGet, "/evaluations/"+url.PathEscape(id), nil, evaluation); err != nil {
+		return nil, fmt.Errorf("error getting evaluation %s: %w", id, err)
+	}
+	return evaluation, nil
+}
+
+// WaitForEvaluation polls the evaluation until it completes or fails, or until
+// ctx is done.
+func (c *Client) WaitForEvaluation(ctx context.Context, id string) (*Evaluation, error) {
+	ticker := time.NewTicker(c.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		evaluation, err := c.GetEvaluation(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+
+		switch evaluation.Status {
+		case EvaluationCompleted:
+			return evaluation, nil
+		case EvaluationFailed:
+			return evaluation, fmt.Errorf("evaluation %s failed: %s", id, evaluation.Error)
+		}
+
+		select {
+		case <-ctx.Done():
+			return evaluation, fmt.Errorf("waiting for evaluation %s: %w", id, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunEvaluation starts an evaluation and waits for its results.
+func (c *Client) RunEvaluation(ctx context.Context, appID string, index string) (*Evaluation, error) {
+	evaluation, err := c.StartEvaluation(ctx, appID, index)
+	if err != nil {
+		return nil, err
+	}
+	return c.WaitForEvaluation(ctx, evaluation.ID)
+}
+
+func (c *Client) do(ctx context.Context, method string, path string, body []byte, out any) error {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.host+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("error reading response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s: %s", resp.Status, strings.TrimSpace(string(data)))
+	}
+
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("error decoding response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+package rankee_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"agent/rankee"
+	"agent/rankee/rankeetest"
+)
+
+func newClient(t *testing.T, fake *rankeetest.Fake) *rankee.Client {
+	t.Helper()
+	server := rankeetest.NewServer(fake)
+	t.Cleanup(server.Close)
+	client := rankee.NewClient(server.URL + "/")
+	client.PollInterval = time.Millisecond
+	return client
+}
+
+func TestStartEvaluation(t *testing.T) {
+	client := newClient(t, rankeetest.NewFake())
+
+	evaluation, err := client.StartEvaluation(context.Background(), "app-1", "products")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if evaluation.ID == "" {
+		t.Error("evaluation has no ID")
+	}
+	if evaluation.AppId != "app-1" || evaluation.Index != "products" {
+		t.Errorf("got app %q index %q, want app-1 products", evaluation.AppId, evaluation.Index)
+	}
+	if evaluation.Status != rankee.EvaluationPending {
+		t.Errorf("got status %q, want %q", evaluation.Status, rankee.EvaluationPending)
+	}
+}
+
+func TestGetEvaluationStatus(t *testing.T) {
+	fake := rankeetest.NewFake()
+	fake.PendingPolls = 2
+	client := newClient(t, fake)
+	ctx := context.Background()
+
+	started, err := client.StartEvaluation(ctx, "app-1", "products")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{rankee.EvaluationRunning, rankee.EvaluationRunning, rankee.EvaluationCompleted}
+	for i, status := range want {
+		evaluation, err := client.GetEvaluation(ctx, started.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if evaluation.Status != status {
+			t.Errorf("poll %d: got status %q, want %q", i+1, evaluation.Status, status)
+		}
+		if (evaluation.Results != nil) != (status == rankee.EvaluationCompleted) {
+			t.Errorf("poll %d: got results %v with status %q", i+1, evaluation.Results, evaluation.Status)
+		}
+	}
+}
+
+func TestRunEvaluationDecodesResults(t *testing.T) {
+	fake := rankeetest.NewFake()
+	fake.PendingPolls = 3
+	client := newClient(t, fake)
+
+	evaluation, err := client.RunEvaluation(context.Background(), "app-1", "products")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fake.Polls(evaluation.ID); got != 4 {
+		t.Errorf("got %d polls, want 4", got)
+	}
+	if evaluation.Results == nil {
+		t.Fatal("completed evaluation has no results")
+	}
+	if !reflect.DeepEqual(*evaluation.Results, fake.Results) {
+		t.Errorf("got results %+v, want %+v", *evaluation.Results, fake.Results)
+	}
+	if got := evaluation.Results.Queries[0].Metrics["ndcg@10"]; got != 0.91 {
+		t.Errorf("got ndcg@10 %v, want 0.91", got)
+	}
+}
+
+func TestRunEvaluationFailed(t *testing.T) {
+	fake := rankeetest.NewFake()
+	fake.Error = "index not found"
+	client := newClient(t, fake)
+
+	evaluation, err := client.RunEvaluation(context.Background(), "app-1", "missing")
+	if err == nil || !strings.Contains(err.Error(), "index not found") {
+		t.Fatalf("got error %v, want the evaluation error", err)
+	}
+	if evaluation == nil || evaluation.Status != rankee.EvaluationFailed {
+		t.Errorf("got evaluation %+v, want a failed one", evaluation)
+	}
+}
+
+func TestWaitForEvaluationCanceled(t *testing.T) {
+	fake := rankeetest.NewFake()
+	fake.PendingPolls = 1 << 30
+	client := newClient(t, fake)
+
+	started, err := client.StartEvaluation(context.Background(), "app-1", "products")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := client.WaitForEvaluation(ctx, started.ID); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got error %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestHTTPErrors(t *testing.T) {
+	client := newClient(t, rankeetest.NewFake())
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		call func() error
+		want string
+	}{
+		{
+			name: "bad request",
+			call: func() error {
+				_, err := client.StartEvaluation(ctx, "", "products")
+				return err
+			},
+			want: "400 Bad Request: appId and index are required",
+		},
+		{
+			name: "not found",
+			call: func() error {
+				_, err := client.GetEvaluation(ctx, "eval-404")
+				return err
+			},
+			want: "404 Not Found: evaluation not found",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.call()
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("got error %v, want it to contain %q", err, test.want)
+			}
+		})
+	}
+}
+
+func TestInvalidResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<html>gateway timeout</html>"))
+	}))
+	defer server.Close()
+
+	_, err := rankee.NewClient(server.URL).GetEvaluation(context.Background(), "eval-1")
+	if err == nil || !strings.Contains(err.Error(), "error decoding response") {
+		t.Errorf("got error %v, want a decoding error", err)
+	}
+}
@@ -0,0 +1,125 @@
+// Package rankeetest provides an in-memory Rankee server for tests.
+package rankeetest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+
+	"agent/rankee"
+)
+
+// Fake is an in-memory stand-in for the Rankee service. Every evaluation
+// reports "running" for PendingPolls status requests and then completes with
+// Results, or fails with Error when it is set.
+type Fake struct {
+	PendingPolls int
+	Results      rankee.EvaluationResults
+	Error        string
+
+	mu          sync.Mutex
+	evaluations map[string]*fakeEvaluation
+	nextID      int
+}
+
+type fakeEvaluation struct {
+	evaluation rankee.Evaluation
+	polls      int
+}
+
+func NewFake() *Fake {
+	return &Fake{
+		PendingPolls: 1,
+		Results: rankee.EvaluationResults{
+			OverallScore: 0.82,
+			Queries: []rankee.QueryMetrics{
+				{Query: "software engineer", Metrics: map[string]float64{"ndcg@10": 0.91, "precision@10": 0.8}},
+				{Query: "lawyer admission", Metrics: map[string]float64{"ndcg@10": 0.73, "precision@10": 0.6}},
+			},
+		},
+		evaluations: make(map[string]*fakeEvaluation),
+	}
+}
+
+// NewServer starts an httptest server backed by f. Callers must Close it.
+func NewServer(f *Fake) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /evaluations", f.startEvaluation)
+	mux.HandleFunc("GET /evaluations/{id}", f.getEvaluation)
+	return httptest.NewServer(mux)
+}
+
+// Polls returns how many times the status of the evaluation with id was
+// requested.
+func (f *Fake) Polls(id string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if e, ok := f.evaluations[id]; ok {
+		return e.polls
+	}
+	return 0
+}
+
+func (f *Fake) startEvaluation(w http.ResponseWriter, r *http.Request) {
+	var req rankee.StartEvaluationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if strings.TrimSpace(req.AppId) == "" || strings.TrimSpace(req.Index) == "" {
+		http.Error(w, "appId and index are required", http.StatusBadRequest)
+		return
+	}
+
+	f.mu.Lock()
+	f.nextID++
+	e := &fakeEvaluation{
+		evaluation: rankee.Evaluation{
+			ID:     fmt.Sprintf("eval-%d", f.nextID),
+			AppId:  req.AppId,
+			Index:  req.Index,
+			Status: rankee.EvaluationPending,
+		},
+	}
+	f.evaluations[e.evaluation.ID] = e
+	evaluation := e.evaluation
+	f.mu.Unlock()
+
+	writeJSON(w, http.StatusAccepted, evaluation)
+}
+
+func (f *Fake) getEvaluation(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	e, ok := f.evaluations[r.PathValue("id")]
+	if !ok {
+		f.mu.Unlock()
+		http.Error(w, "evaluation not found", http.StatusNotFound)
+		return
+	}
+
+	e.polls++
+	switch {
+	case e.polls <= f.PendingPolls:
+		e.evaluation.Status = rankee.EvaluationRunning
+	case f.Error != "":
+		e.evaluation.Status = rankee.EvaluationFailed
+		e.evaluation.Error = f.Error
+	default:
+		results := f.Results
+		e.evaluation.Status = rankee.EvaluationCompleted
+		e.evaluation.Results = &results
+	}
+	evaluation := e.evaluation
+	f.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, evaluation)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
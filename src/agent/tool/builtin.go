@@ -0,0 +1,67 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"agent/rankee"
+)
+
+const rankeeEvaluationTimeout = 5 * time.Minute
+
+// NewDefaultRegistry returns a registry of the tools both agents offer,
+// get_weather and run_rankee_evaluation, which runs evaluations with client.
+func NewDefaultRegistry(client *rankee.Client) (*Registry, error) {
+	r := NewRegistry()
+	if err := Register(r, "get_weather", "Get weather at the given location", GetWeather); err != nil {
+		return nil, err
+	}
+	if err := Register(r, "run_rankee_evaluation", "Run a Rankee evaluation with given parameters", RunRankeeEvaluation(client)); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+type GetWeatherArgs struct {
+	Location string `json:"location" desc:"The city or place to get the weather for"`
+}
+
+// Mock function to simulate weather data retrieval
+func GetWeather(ctx context.Context, args GetWeatherArgs) (string, error) {
+	if args.Location == "" {
+		return "", fmt.Errorf("location is required")
+	}
+	// In a real implementation, this function would call a weather API
+	return "Sunny, 25°C", nil
+}
+
+type RankeeEvaluationArgs struct {
+	AppId string `json:"AppId" desc:"The Rankee application ID"`
+	Index string `json:"Index" desc:"The search index to evaluate"`
+}
+
+// RunRankeeEvaluation returns a tool that runs an evaluation with client and
+// hands the results back to the model as JSON.
+func RunRankeeEvaluation(client *rankee.Client) func(ctx context.Context, args RankeeEvaluationArgs) (string, error) {
+	return func(ctx context.Context, args RankeeEvaluationArgs) (string, error) {
+		if args.AppId == "" || args.Index == "" {
+			return "", fmt.Errorf("AppId and Index are required")
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, rankeeEvaluationTimeout)
+		defer cancel()
+
+		evaluation, err := client.RunEvaluation(ctx, args.AppId, args.Index)
+		if err != nil {
+			return "", err
+		}
+
+		result, err := json.Marshal(evaluation.Results)
+		if err != nil {
+			return "", fmt.Errorf("error encoding evaluation results: %w", err)
+		}
+		return string(result), nil
+	}
+}
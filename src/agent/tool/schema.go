@@ -0,0 +1,203 @@
+package tool
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// schema is the subset of JSON Schema needed to describe tool arguments.
+// It is generated from Go struct fields using the following tags:
+//
+//	json:"name,omitempty"   property name; fields without omitempty are required
+//	desc:"..."              property description shown to the model
+//	enum:"a,b,c"            allowed values for a string property
+type schema struct {
+	Type        string             `json:"type"`
+	Description string             `json:"description,omitempty"`
+	Enum        []string           `json:"enum,omitempty"`
+	Items       *schema            `json:"items,omitempty"`
+	Properties  map[string]*schema `json:"properties,omitempty"`
+	Required    []string           `json:"required,omitempty"`
+}
+
+func schemaFor(v any) (*schema, error) {
+	return schemaForType(reflect.TypeOf(v))
+}
+
+func schemaForType(t reflect.Type) (*schema, error) {
+	if t == nil {
+		return nil, fmt.Errorf("cannot generate schema for nil type")
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &schema{Type: "string"}, nil
+	case reflect.Bool:
+		return &schema{Type: "boolean"}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &schema{Type: "integer"}, nil
+	case reflect.Float32, reflect.Float64:
+		return &schema{Type: "number"}, nil
+	case reflect.Slice, reflect.Array:
+		items, err := schemaForType(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return &schema{Type: "array", Items: items}, nil
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("unsupported map key type %s", t.Key())
+		}
+		return &schema{Type: "object"}, nil
+	case reflect.Struct:
+		return schemaForStruct(t)
+	default:
+		return nil, fmt.Errorf("unsupported type %s", t)
+	}
+}
+
+func schemaForStruct(t reflect.Type) (*schema, error) {
+	s := &schema{
+		Type:       "object",
+		Properties: make(map[string]*schema),
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, omitempty := parseJSONTag(field)
+		if name == "-" {
+			continue
+		}
+
+		property, err := schemaForType(field.Type)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", field.Name, err)
+		}
+		property.Description = field.Tag.Get("desc")
+		if enum := field.Tag.Get("enum"); enum != "" {
+			property.Enum = strings.Split(enum, ",")
+		}
+
+		s.Properties[name] = property
+		if !omitempty {
+			s.Required = append(s.Required, name)
+		}
+	}
+
+	return s, nil
+}
+
+func parseJSONTag(field reflect.StructField) (name string, omitempty bool) {
+	name = field.Name
+	tag, ok := field.Tag.Lookup("json")
+	if !ok {
+		return name, false
+	}
+
+	parts := strings.Split(tag, ",")
+	if parts[0] != "" {
+		name = parts[0]
+	}
+	return name, slices.Contains(parts[1:], "omitempty")
+}
+
+// parameters converts the schema into the generic map sent to the model as
+// tool parameters.
+func (s *schema) parameters() map[string]any {
+	data, _ := json.Marshal(s)
+	params := map[string]any{}
+	_ = json.Unmarshal(data, &params)
+	return params
+}
+
+// validate checks the raw arguments against the schema and decodes them into out.
+func (s *schema) validate(raw json.RawMessage, out any) error {
+	var value any
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return fmt.Errorf("arguments are not valid JSON: %w", err)
+	}
+	if err := s.check("arguments", value); err != nil {
+		return err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(out); err != nil {
+		return fmt.Errorf("invalid arguments: %w", err)
+	}
+	return nil
+}
+
+func (s *schema) check(path string, value any) error {
+	switch s.Type {
+	case "object":
+		object, ok := value.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s must be an object", path)
+		}
+		for _, name := range s.Required {
+			if _, ok := object[name]; !ok {
+				return fmt.Errorf("%s is missing required field %q", path, name)
+			}
+		}
+		if s.Properties == nil {
+			return nil
+		}
+		for name, v := range object {
+			property, ok := s.Properties[name]
+			if !ok {
+				return fmt.Errorf("%s has unknown field %q", path, name)
+			}
+			if v == nil && !slices.Contains(s.Required, name) {
+				continue
+			}
+			if err := property.check(path+"."+name, v); err != nil {
+				return err
+			}
+		}
+	case "array":
+		array, ok := value.([]any)
+		if !ok {
+			return fmt.Errorf("%s must be an array", path)
+		}
+		for i, v := range array {
+			if err := s.Items.check(fmt.Sprintf("%s[%d]", path, i), v); err != nil {
+				return err
+			}
+		}
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("%s must be a string", path)
+		}
+		if len(s.Enum) > 0 && !slices.Contains(s.Enum, str) {
+			return fmt.Errorf("%s must be one of %v, got %q", path, s.Enum, str)
+		}
+	case "integer":
+		number, ok := value.(float64)
+		if !ok || number != float64(int64(number)) {
+			return fmt.Errorf("%s must be an integer", path)
+		}
+	case "number":
+		if _, ok := value.(float64); !ok {
+			return fmt.Errorf("%s must be a number", path)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("%s must be a boolean", path)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+// Package tool is the registry of Go functions the agents let a model call.
+// The JSON Schema sent to the model is generated from the argument struct of
+// each function, and the arguments of every call are validated against it.
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/jacygao/ai/llm"
+)
+
+// Registry is the set of functions the model is allowed to call.
+type Registry struct {
+	registered []*entry
+	byName     map[string]*entry
+}
+
+type entry struct {
+	name        string
+	description string
+	schema      *schema
+	call        func(ctx context.Context, arguments json.RawMessage) (string, error)
+}
+
+func NewRegistry() *Registry {
+	return &Registry{byName: make(map[string]*entry)}
+}
+
+// Register adds fn to the registry under name. The JSON Schema sent to the model
+// is generated from the fields of T, and the arguments of every call are validated
+// against it before fn runs.
+func Register[T any](r *Registry, name, description string, fn func(ctx context.Context, args T) (string, error)) error {
+	if _, exists := r.byName[name]; exists {
+		return fmt.Errorf("tool %q is already registered", name)
+	}
+
+	var zero T
+	s, err := schemaFor(zero)
+	if err != nil {
+		return fmt.Errorf("tool %q: %w", name, err)
+	}
+	if s.Type != "object" {
+		return fmt.Errorf("tool %q: arguments must be a struct, got %s", name, s.Type)
+	}
+
+	registered := &entry{
+		name:        name,
+		description: description,
+		schema:      s,
+		call: func(ctx context.Context, arguments json.RawMessage) (string, error) {
+			var args T
+			if err := s.validate(arguments, &args); err != nil {
+				return "", err
+			}
+			return fn(ctx, args)
+		},
+	}
+	r.registered = append(r.registered, registered)
+	r.byName[name] = registered
+	return nil
+}
+
+// Definitions returns the registered tools in the form sent to the model.
+func (r *Registry) Definitions() []llm.Tool {
+	definitions := make([]llm.Tool, 0, len(r.registered))
+	for _, e := range r.registered {
+		definitions = append(definitions, llm.Tool{
+			Name:        e.name,
+			Description: e.description,
+			Parameters:  e.schema.parameters(),
+		})
+	}
+	return definitions
+}
+
+// Call runs the named tool with the raw JSON arguments produced by the model.
+// Unknown tools, invalid arguments and tool failures are returned as errors,
+// the agents report them to the model so it can correct itself.
+func (r *Registry) Call(ctx context.Context, name string, arguments json.RawMessage) (string, error) {
+	e, ok := r.byName[name]
+	if !ok {
+		return "", fmt.Errorf("unknown tool %q, available tools: %v", name, r.names())
+	}
+	if len(arguments) == 0 {
+		arguments = json.RawMessage("{}")
+	}
+	return e.call(ctx, arguments)
+}
+
+func (r *Registry) names() []string {
+	names := make([]string, 0, len(r.byName))
+	for name := range r.byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,146 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"agent/rankee"
+	"agent/rankee/rankeetest"
+)
+
+type searchArgs struct {
+	Query   string   `json:"query" desc:"What to search for"`
+	Sort    string   `json:"sort,omitempty" enum:"relevance,date"`
+	Limit   int      `json:"limit,omitempty"`
+	Tags    []string `json:"tags,omitempty"`
+	Exact   bool     `json:"exact,omitempty"`
+	Ignored string   `json:"-"`
+}
+
+func TestSchemaFor(t *testing.T) {
+	s, err := schemaFor(searchArgs{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"query": map[string]any{"type": "string", "description": "What to search for"},
+			"sort":  map[string]any{"type": "string", "enum": []any{"relevance", "date"}},
+			"limit": map[string]any{"type": "integer"},
+			"tags":  map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+			"exact": map[string]any{"type": "boolean"},
+		},
+		"required": []any{"query"},
+	}
+	if got := s.parameters(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	if _, err := schemaFor(struct{ C chan int }{}); err == nil {
+		t.Error("got no error for a channel field")
+	}
+}
+
+func TestCall(t *testing.T) {
+	r := NewRegistry()
+	var got searchArgs
+	err := Register(r, "search", "Search", func(ctx context.Context, args searchArgs) (string, error) {
+		got = args
+		return "found", nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		arguments string
+		err       string
+	}{
+		{name: "null optional", arguments: `{"query":"go","sort":null}`},
+		{name: "missing required", arguments: `{}`, err: `arguments is missing required field "query"`},
+		{name: "unknown field", arguments: `{"query":"go","page":2}`, err: `arguments has unknown field "page"`},
+		{name: "wrong type", arguments: `{"query":1}`, err: "arguments.query must be a string"},
+		{name: "not in enum", arguments: `{"query":"go","sort":"name"}`, err: "arguments.sort must be one of [relevance date]"},
+		{name: "fraction", arguments: `{"query":"go","limit":1.5}`, err: "arguments.limit must be an integer"},
+		{name: "array item", arguments: `{"query":"go","tags":[1]}`, err: "arguments.tags[0] must be a string"},
+		{name: "invalid JSON", arguments: `{`, err: "arguments are not valid JSON"},
+		{name: "valid", arguments: `{"query":"go","sort":"date","limit":3,"tags":["a"]}`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := r.Call(context.Background(), "search", json.RawMessage(test.arguments))
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Errorf("got error %v, want %q", err, test.err)
+				}
+				return
+			}
+			if err != nil || result != "found" {
+				t.Errorf("got %q, %v", result, err)
+			}
+		})
+	}
+	if got.Query != "go" || got.Sort != "date" || got.Limit != 3 || !reflect.DeepEqual(got.Tags, []string{"a"}) {
+		t.Errorf("got arguments %+v", got)
+	}
+
+	if _, err := r.Call(context.Background(), "browse", nil); err == nil || !strings.Contains(err.Error(), `unknown tool "browse", available tools: [search]`) {
+		t.Errorf("got error %v for an unknown tool", err)
+	}
+}
+
+func TestRegisterErrors(t *testing.T) {
+	r := NewRegistry()
+	fn := func(ctx context.Context, args searchArgs) (string, error) { return "", nil }
+	if err := Register(r, "search", "Search", fn); err != nil {
+		t.Fatal(err)
+	}
+	if err := Register(r, "search", "Search again", fn); err == nil {
+		t.Error("got no error registering a tool twice")
+	}
+	if err := Register(r, "echo", "Echo", func(ctx context.Context, args string) (string, error) { return args, nil }); err == nil {
+		t.Error("got no error for arguments that are not a struct")
+	}
+	if got := len(r.Definitions()); got != 1 {
+		t.Errorf("got %d definitions, want 1", got)
+	}
+}
+
+func TestDefaultRegistry(t *testing.T) {
+	server := rankeetest.NewServer(rankeetest.NewFake())
+	t.Cleanup(server.Close)
+	client := rankee.NewClient(server.URL)
+	client.PollInterval = time.Millisecond
+
+	r, err := NewDefaultRegistry(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, definition := range r.Definitions() {
+		names = append(names, definition.Name)
+	}
+	if want := []string{"get_weather", "run_rankee_evaluation"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("got tools %v, want %v", names, want)
+	}
+
+	result, err := r.Call(context.Background(), "run_rankee_evaluation", json.RawMessage(`{"AppId":"app-1","Index":"products"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var results rankee.EvaluationResults
+	if err := json.Unmarshal([]byte(result), &results); err != nil || results.OverallScore != 0.82 {
+		t.Errorf("got %q, %v, want evaluation results", result, err)
+	}
+
+	if _, err := r.Call(context.Background(), "run_rankee_evaluation", json.RawMessage(`{"AppId":"","Index":"products"}`)); err == nil {
+		t.Error("got no error for an empty AppId")
+	}
+}
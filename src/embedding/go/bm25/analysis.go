@@ -0,0 +1,267 @@
+package bm25
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Token is a term and the position of the word it came from, counted before
+// any tokens are dropped so that gaps left by stopwords are kept.
+type Token struct {
+	Term     string
+	Position int
+}
+
+// Tokenizer splits text into tokens.
+type Tokenizer func(text string) []Token
+
+// Filter transforms a token stream, e.g. lower casing, dropping or adding tokens.
+type Filter func(tokens []Token) []Token
+
+// Analyzer turns text into the terms that are indexed and searched for. An
+// index uses its analyzer for both documents and queries, so the two always
+// agree on what a term is.
+type Analyzer struct {
+	Tokenizer Tokenizer
+	Filters   []Filter
+
+	// config describes the analyzer when it was built from an
+	// AnalyzerConfig, which is what lets an index using it be saved.
+	config *AnalyzerConfig
+}
+
+func NewAnalyzer(tokenizer Tokenizer, filters ...Filter) *Analyzer {
+	return &Analyzer{Tokenizer: tokenizer, Filters: filters}
+}
+
+// AnalyzerConfig describes an analyzer built from the tokenizers and filters
+// of this package. Filters are applied in the order of the fields.
+type AnalyzerConfig struct {
+	// Tokenizer is "unicode" (UnicodeTokenizer, the default) or "whitespace".
+	Tokenizer        string   `json:"tokenizer,omitempty"`
+	StripPunctuation bool     `json:"strip_punctuation,omitempty"`
+	Lowercase        bool     `json:"lowercase,omitempty"`
+	Stopwords        []string `json:"stopwords,omitempty"`
+	Stem             bool     `json:"stem,omitempty"`
+	// NGramMin and NGramMax enable character n-grams when NGramMax is set.
+	NGramMin int `json:"ngram_min,omitempty"`
+	NGramMax int `json:"ngram_max,omitempty"`
+	// Shingles enables word shingles when above 1.
+	Shingles int `json:"shingles,omitempty"`
+}
+
+// Build returns the analyzer the config describes.
+func (c AnalyzerConfig) Build() (*Analyzer, error) {
+	a := &Analyzer{config: &c}
+	switch c.Tokenizer {
+	case "", "unicode":
+		a.Tokenizer = UnicodeTokenizer
+	case "whitespace":
+		a.Tokenizer = WhitespaceTokenizer
+	default:
+		return nil, fmt.Errorf("bm25: unknown tokenizer %q", c.Tokenizer)
+	}
+
+	if c.StripPunctuation {
+		a.Filters = append(a.Filters, StripPunctuation)
+	}
+	if c.Lowercase {
+		a.Filters = append(a.Filters, Lowercase)
+	}
+	if len(c.Stopwords) > 0 {
+		words := make(map[string]bool, len(c.Stopwords))
+		for _, word := range c.Stopwords {
+			words[word] = true
+		}
+		a.Filters = append(a.Filters, Stopwords(words))
+	}
+	if c.Stem {
+		a.Filters = append(a.Filters, Stem)
+	}
+	if c.NGramMax > 0 {
+		if c.NGramMin < 1 || c.NGramMin > c.NGramMax {
+			return nil, fmt.Errorf("bm25: invalid n-gram lengths %d to %d", c.NGramMin, c.NGramMax)
+		}
+		a.Filters = append(a.Filters, NGrams(c.NGramMin, c.NGramMax))
+	}
+	if c.Shingles > 1 {
+		a.Filters = append(a.Filters, Shingles(c.Shingles))
+	}
+	return a, nil
+}
+
+// Config returns the config the analyzer was built from, or false for
+// analyzers made with NewAnalyzer.
+func (a *Analyzer) Config() (AnalyzerConfig, bool) {
+	if a.config == nil {
+		return AnalyzerConfig{}, false
+	}
+	return *a.config, true
+}
+
+// StandardAnalyzer splits text into lower cased words. It does not stem, so
+// "Charlotte's" and "Charlotte" are different terms; English text is better
+// served by EnglishAnalyzer.
+func StandardAnalyzer() *Analyzer {
+	a, _ := AnalyzerConfig{Lowercase: true}.Build()
+	return a
+}
+
+// EnglishAnalyzer splits text into lower cased words, drops the stopwords
+// and reduces the rest to their Porter stems.
+func EnglishAnalyzer(stopwords map[string]bool) *Analyzer {
+	a, _ := AnalyzerConfig{Lowercase: true, Stopwords: SortedWords(stopwords), Stem: true}.Build()
+	return a
+}
+
+// Analyze runs the tokenizer and the filters in order.
+func (a *Analyzer) Analyze(text string) []Token {
+	tokens := a.Tokenizer(text)
+	for _, filter := range a.Filters {
+		tokens = filter(tokens)
+	}
+	return tokens
+}
+
+// Terms returns the terms of the analyzed text.
+func (a *Analyzer) Terms(text string) []string {
+	tokens := a.Analyze(text)
+	terms := make([]string, len(tokens))
+	for i, token := range tokens {
+		terms[i] = token.Term
+	}
+	return terms
+}
+
+// WhitespaceTokenizer splits text on whitespace, leaving punctuation attached.
+func WhitespaceTokenizer(text string) []Token {
+	var tokens []Token
+	for i, word := range strings.Fields(text) {
+		tokens = append(tokens, Token{Term: word, Position: i})
+	}
+	return tokens
+}
+
+// UnicodeTokenizer splits text into runs of letters, digits and marks in any
+// script. Apostrophes inside a word are kept, so "Charlotte's" is one token.
+func UnicodeTokenizer(text string) []Token {
+	var tokens []Token
+	runes := []rune(text)
+	start := -1
+	for i := 0; i <= len(runes); i++ {
+		if i < len(runes) && isWordRune(runes, i) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 {
+			tokens = append(tokens, Token{Term: string(runes[start:i]), Position: len(tokens)})
+			start = -1
+		}
+	}
+	return tokens
+}
+
+func isWordRune(runes []rune, i int) bool {
+	r := runes[i]
+	if unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r) {
+		return true
+	}
+	// An apostrophe between two letters
+	return (r == '\'' || r == '’') && i > 0 && i+1 < len(runes) &&
+		unicode.IsLetter(runes[i-1]) && unicode.IsLetter(runes[i+1])
+}
+
+// Lowercase lower cases every token.
+func Lowercase(tokens []Token) []Token {
+	for i := range tokens {
+		tokens[i].Term = strings.ToLower(tokens[i].Term)
+	}
+	return tokens
+}
+
+// StripPunctuation trims punctuation and symbols from both ends of every
+// token and drops tokens that were only punctuation.
+func StripPunctuation(tokens []Token) []Token {
+	kept := tokens[:0]
+	for _, token := range tokens {
+		token.Term = strings.TrimFunc(token.Term, func(r rune) bool {
+			return unicode.IsPunct(r) || unicode.IsSymbol(r)
+		})
+		if token.Term != "" {
+			kept = append(kept, token)
+		}
+	}
+	return kept
+}
+
+// Stopwords drops the tokens in words. Filters run in order, so the words
+// must be in the form the earlier filters produce, normally lower case.
+func Stopwords(words map[string]bool) Filter {
+	return func(tokens []Token) []Token {
+		kept := tokens[:0]
+		for _, token := range tokens {
+			if !words[token.Term] {
+				kept = append(kept, token)
+			}
+		}
+		return kept
+	}
+}
+
+// Stem reduces every token to its Porter stem, so "engineers" and
+// "engineering" both match "engineer". It expects lower case tokens.
+func Stem(tokens []Token) []Token {
+	for i := range tokens {
+		tokens[i].Term = PorterStem(tokens[i].Term)
+	}
+	return tokens
+}
+
+// NGrams replaces every token with its character n-grams of minLength to
+// maxLength runes, which lets partial words and misspellings match. Tokens
+// shorter than minLength are kept whole.
+func NGrams(minLength int, maxLength int) Filter {
+	return func(tokens []Token) []Token {
+		var grams []Token
+		for _, token := range tokens {
+			runes := []rune(token.Term)
+			if len(runes) < minLength {
+				grams = append(grams, token)
+				continue
+			}
+			for n := minLength; n <= maxLength && n <= len(runes); n++ {
+				for start := 0; start+n <= len(runes); start++ {
+					grams = append(grams, Token{Term: string(runes[start : start+n]), Position: token.Position})
+				}
+			}
+		}
+		return grams
+	}
+}
+
+// Shingles adds word n-grams of 2 to size adjacent tokens, joined by a
+// space, after the single tokens. Documents containing the words of a query
+// next to each other then score higher. Words separated by a dropped
+// stopword are not adjacent. A shingle takes the position of its first word.
+func Shingles(size int) Filter {
+	return func(tokens []Token) []Token {
+		shingles := tokens
+		for n := 2; n <= size; n++ {
+		next:
+			for start := 0; start+n <= len(tokens); start++ {
+				words := make([]string, n)
+				for i := range words {
+					if tokens[start+i].Position != tokens[start].Position+i {
+						continue next
+					}
+					words[i] = tokens[start+i].Term
+				}
+				shingles = append(shingles, Token{Term: strings.Join(words, " "), Position: tokens[start].Position})
+			}
+		}
+		return shingles
+	}
+}
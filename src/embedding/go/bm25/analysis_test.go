@@ -0,0 +1,187 @@
+package bm25
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// tokens builds the expected tokens from "term@position" pairs.
+func tokens(pairs ...string) []Token {
+	var out []Token
+	for _, pair := range pairs {
+		i := strings.LastIndexByte(pair, '@')
+		position := 0
+		for _, digit := range pair[i+1:] {
+			position = position*10 + int(digit-'0')
+		}
+		out = append(out, Token{Term: pair[:i], Position: position})
+	}
+	return out
+}
+
+func TestUnicodeTokenizer(t *testing.T) {
+	tests := []struct {
+		text string
+		want []Token
+	}{
+		{"", nil},
+		{"  ...  ", nil},
+		{"Charlotte's web", tokens("Charlotte's@0", "web@1")},
+		{"Charlotte’s web", tokens("Charlotte’s@0", "web@1")},
+		{"rock 'n' roll", tokens("rock@0", "n@1", "roll@2")},
+		{"the spiders' webs", tokens("the@0", "spiders@1", "webs@2")},
+		{"covid-19, 2024!", tokens("covid@0", "19@1", "2024@2")},
+		{"naïve café—résumé", tokens("naïve@0", "café@1", "résumé@2")},
+		// e followed by a combining acute accent
+		{"cafe\u0301 au lait", tokens("cafe\u0301@0", "au@1", "lait@2")},
+		{"東京タワー に 行く", tokens("東京タワー@0", "に@1", "行く@2")},
+		{"Привет, мир", tokens("Привет@0", "мир@1")},
+	}
+	for _, test := range tests {
+		if got := UnicodeTokenizer(test.text); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("UnicodeTokenizer(%q): got %v, want %v", test.text, got, test.want)
+		}
+	}
+}
+
+func TestFilters(t *testing.T) {
+	stopwords := Stopwords(map[string]bool{"the": true, "a": true, "is": true})
+	tests := []struct {
+		name     string
+		analyzer *Analyzer
+		text     string
+		want     []Token
+	}{
+		{
+			name:     "whitespace keeps punctuation",
+			analyzer: NewAnalyzer(WhitespaceTokenizer),
+			text:     "Hello, (world)!",
+			want:     tokens("Hello,@0", "(world)!@1"),
+		},
+		{
+			name:     "strip punctuation",
+			analyzer: NewAnalyzer(WhitespaceTokenizer, StripPunctuation),
+			text:     "Hello, (world)! -- c++ $5",
+			want:     tokens("Hello@0", "world@1", "c@3", "5@4"),
+		},
+		{
+			name:     "lowercase",
+			analyzer: NewAnalyzer(UnicodeTokenizer, Lowercase),
+			text:     "Wilbur ÉCLAIR",
+			want:     tokens("wilbur@0", "éclair@1"),
+		},
+		{
+			name:     "stopwords keep the positions of the other words",
+			analyzer: NewAnalyzer(UnicodeTokenizer, Lowercase, stopwords),
+			text:     "The pig is a friend",
+			want:     tokens("pig@1", "friend@4"),
+		},
+		{
+			name:     "stopwords are matched after the earlier filters",
+			analyzer: NewAnalyzer(UnicodeTokenizer, stopwords),
+			text:     "The pig",
+			want:     tokens("The@0", "pig@1"),
+		},
+		{
+			name:     "stem",
+			analyzer: NewAnalyzer(UnicodeTokenizer, Lowercase, Stem),
+			text:     "Charlotte's webs",
+			want:     tokens("charlott@0", "web@1"),
+		},
+		{
+			name:     "n-grams",
+			analyzer: NewAnalyzer(UnicodeTokenizer, NGrams(2, 3)),
+			text:     "a pig",
+			want:     tokens("a@0", "pi@1", "ig@1", "pig@1"),
+		},
+		{
+			name:     "n-grams of runes",
+			analyzer: NewAnalyzer(UnicodeTokenizer, NGrams(3, 3)),
+			text:     "café",
+			want:     tokens("caf@0", "afé@0"),
+		},
+		{
+			name:     "shingles",
+			analyzer: NewAnalyzer(UnicodeTokenizer, Shingles(3)),
+			text:     "some pig indeed",
+			want:     tokens("some@0", "pig@1", "indeed@2", "some pig@0", "pig indeed@1", "some pig indeed@0"),
+		},
+		{
+			name:     "no shingles across dropped stopwords",
+			analyzer: NewAnalyzer(UnicodeTokenizer, Lowercase, stopwords, Shingles(2)),
+			text:     "Wilbur is terrific pig",
+			want:     tokens("wilbur@0", "terrific@2", "pig@3", "terrific pig@2"),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.analyzer.Analyze(test.text); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestAnalyzers(t *testing.T) {
+	tests := []struct {
+		name     string
+		analyzer *Analyzer
+		text     string
+		want     []string
+	}{
+		{
+			name:     "standard",
+			analyzer: StandardAnalyzer(),
+			text:     "Charlotte's webs and the pig",
+			want:     []string{"charlotte's", "webs", "and", "the", "pig"},
+		},
+		{
+			name:     "english",
+			analyzer: EnglishAnalyzer(EnglishStopwords()),
+			text:     "Charlotte's webs and the pig",
+			want:     []string{"charlott", "web", "pig"},
+		},
+		{
+			name:     "english matches other forms of a word",
+			analyzer: EnglishAnalyzer(EnglishStopwords()),
+			text:     "Charlotte engineering engineers",
+			want:     []string{"charlott", "engin", "engin"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.analyzer.Terms(test.text); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestAnalyzerConfig(t *testing.T) {
+	config := AnalyzerConfig{Tokenizer: "whitespace", StripPunctuation: true, Lowercase: true, Stopwords: []string{"the"}, Stem: true, Shingles: 2}
+	analyzer, err := config.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := analyzer.Config(); !ok || !reflect.DeepEqual(got, config) {
+		t.Errorf("got config %+v, %t, want %+v", got, ok, config)
+	}
+	want := []string{"engin", "hop", "engin hop"}
+	if got := analyzer.Terms("The Engineers, hopping."); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if _, ok := NewAnalyzer(UnicodeTokenizer).Config(); ok {
+		t.Error("got a config for an analyzer made with NewAnalyzer")
+	}
+
+	for _, config := range []AnalyzerConfig{
+		{Tokenizer: "letters"},
+		{NGramMin: 0, NGramMax: 3},
+		{NGramMin: 4, NGramMax: 3},
+	} {
+		if _, err := config.Build(); err == nil {
+			t.Errorf("got no error building %+v", config)
+		}
+	}
+}
@@ -0,0 +1,407 @@
+// Package bm25 is an in-memory full text index ranking documents with BM25.
+package bm25
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// Default BM25 parameters
+const (
+	DefaultK1 = 1.5  // Controls term frequency saturation
+	DefaultB  = 0.75 // Controls document length normalization
+)
+
+// DefaultField is the field Add and Update index text into.
+const DefaultField = "content"
+
+var (
+	ErrNotFound  = errors.New("bm25: document not found")
+	ErrDuplicate = errors.New("bm25: document already exists")
+)
+
+// Field sets how a field of the documents counts in BM25F scoring.
+type Field struct {
+	// Weight multiplies the term frequencies of the field.
+	Weight float64 `json:"weight"`
+	// B is the length normalization of the field.
+	B float64 `json:"b"`
+}
+
+// Index holds the postings of a corpus. Analyzer must be set before
+// documents are added, the scoring settings before the index is shared.
+//
+// Documents can be added, updated and deleted while the index is searched:
+// writers are serialised and readers see either the old or the new document.
+type Index struct {
+	K1      float64
+	B       float64
+	Scoring Scoring
+	// Delta is the BM25+ and BM25L term frequency shift. Zero uses 1 for
+	// BM25+ and 0.5 for BM25L.
+	Delta float64
+	// NonNegativeIDF uses log(1 + (N-df+0.5)/(df+0.5)), so terms in more
+	// than half of the documents still add to the score instead of lowering it.
+	NonNegativeIDF bool
+	// Fields weights the fields of multi-field documents (BM25F). Fields
+	// missing from it have weight 1 and use B.
+	Fields map[string]Field
+	// Analyzer turns both documents and queries into terms.
+	Analyzer *Analyzer
+	// MaxScore skips documents that cannot make the top k, see Search.
+	MaxScore bool
+
+	mu           sync.RWMutex
+	docs         []*document    // docID -> document, nil once deleted until Compact
+	ids          map[string]int // document ID -> internal docID
+	fieldIDs     map[string]int // field name -> position in fieldNames
+	fieldNames   []string
+	terms        map[string]*termPostings
+	lengths      [][]int // fieldID -> docID -> number of terms in the field
+	totalLengths []int   // fieldID -> sum of the field's lengths
+}
+
+// document is an indexed document. Postings refer to it by an internal
+// docID, which is never reused so IDs can be deleted and added again. Only
+// Compact renumbers them.
+type document struct {
+	id string
+	// texts is indexed by fieldID and can be shorter than fieldNames when
+	// the document lacks the later fields.
+	texts []string
+}
+
+// termPostings holds the postings of a term in each field.
+type termPostings struct {
+	fields  []*postingList // fieldID -> postings, nil if no document has the term in the field
+	docFreq int            // documents with the term in any field
+}
+
+// Result is a document matching a search. Text is its DefaultField and
+// Fields holds every field it has.
+type Result struct {
+	ID     string
+	Text   string
+	Fields map[string]string
+	Score  float64
+}
+
+func NewIndex() *Index {
+	return &Index{
+		K1:       DefaultK1,
+		B:        DefaultB,
+		Analyzer: StandardAnalyzer(),
+		MaxScore: true,
+		ids:      make(map[string]int),
+		fieldIDs: make(map[string]int),
+		terms:    make(map[string]*termPostings),
+	}
+}
+
+// Add indexes text as the DefaultField of a document with id, which must
+// not be in the index yet.
+func (idx *Index) Add(id string, text string) error {
+	return idx.AddFields(id, map[string]string{DefaultField: text})
+}
+
+// AddFields indexes a document with several fields, e.g. a title and tags
+// next to its content.
+func (idx *Index) AddFields(id string, fields map[string]string) error {
+	analyzed := idx.analyzeFields(fields)
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if _, exists := idx.ids[id]; exists {
+		return fmt.Errorf("%w: %q", ErrDuplicate, id)
+	}
+	idx.add(id, fields, analyzed)
+	return nil
+}
+
+// Update replaces the text of the document with id by a single DefaultField.
+func (idx *Index) Update(id string, text string) error {
+	return idx.UpdateFields(id, map[string]string{DefaultField: text})
+}
+
+// UpdateFields replaces all fields of the document with id.
+func (idx *Index) UpdateFields(id string, fields map[string]string) error {
+	analyzed := idx.analyzeFields(fields)
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	docID, exists := idx.ids[id]
+	if !exists {
+		return fmt.Errorf("%w: %q", ErrNotFound, id)
+	}
+	idx.remove(docID)
+	idx.add(id, fields, analyzed)
+	return nil
+}
+
+// Delete removes the document with id from the index. Its postings are
+// removed right away, but its docID slot is kept until Compact.
+func (idx *Index) Delete(id string) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	docID, exists := idx.ids[id]
+	if !exists {
+		return fmt.Errorf("%w: %q", ErrNotFound, id)
+	}
+	idx.remove(docID)
+	return nil
+}
+
+func (idx *Index) add(id string, fields map[string]string, analyzed map[string][]Token) {
+	// Fields are registered in name order, so the same documents always
+	// give the same fieldIDs
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		idx.fieldID(name)
+	}
+
+	docID := len(idx.docs)
+	doc := &document{id: id}
+	idx.docs = append(idx.docs, doc)
+	idx.ids[id] = docID
+	for fieldID := range idx.lengths {
+		idx.lengths[fieldID] = append(idx.lengths[fieldID], 0)
+	}
+
+	seen := make(map[string]bool)
+	for _, name := range names {
+		text := fields[name]
+		fieldID := idx.fieldIDs[name]
+		for len(doc.texts) <= fieldID {
+			doc.texts = append(doc.texts, "")
+		}
+		tokens := analyzed[name]
+		doc.texts[fieldID] = text
+		idx.lengths[fieldID][docID] = len(tokens)
+		idx.totalLengths[fieldID] += len(tokens)
+
+		termPositions := make(map[string][]int32)
+		for _, token := range tokens {
+			termPositions[token.Term] = append(termPositions[token.Term], int32(token.Position))
+		}
+		for term, positions := range termPositions {
+			// Filters may reorder tokens, postings need positions in order
+			slices.Sort(positions)
+			postings, exists := idx.terms[term]
+			if !exists {
+				postings = &termPostings{}
+				idx.terms[term] = postings
+			}
+			if !seen[term] {
+				seen[term] = true
+				postings.docFreq++
+			}
+			postings.list(fieldID).add(docID, positions)
+		}
+	}
+}
+
+// remove drops a document and its postings. Terms left without documents
+// are removed too, so document frequencies stay exact.
+func (idx *Index) remove(docID int) {
+	doc := idx.docs[docID]
+	seen := make(map[string]bool)
+	for fieldID, text := range doc.texts {
+		for _, term := range idx.analyze(text) {
+			postings, exists := idx.terms[term]
+			if !exists || seen[term] {
+				continue
+			}
+			seen[term] = true
+			for _, list := range postings.fields {
+				if list != nil {
+					list.remove(docID)
+				}
+			}
+			postings.docFreq--
+			if postings.docFreq == 0 {
+				delete(idx.terms, term)
+			}
+		}
+		idx.totalLengths[fieldID] -= idx.lengths[fieldID][docID]
+		idx.lengths[fieldID][docID] = 0
+	}
+
+	delete(idx.ids, doc.id)
+	idx.docs[docID] = nil
+}
+
+// Compact renumbers the documents to reclaim the slots left by deleted and
+// updated ones, a pointer and a length per field each. Indexes that change
+// often should be compacted now and then; saved indexes are loaded compacted.
+func (idx *Index) Compact() {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if len(idx.docs) == len(idx.ids) {
+		return
+	}
+
+	// Documents keep their order, so posting lists stay sorted by docID
+	newIDs := make([]int, len(idx.docs))
+	docs := make([]*document, 0, len(idx.ids))
+	for docID, doc := range idx.docs {
+		if doc == nil {
+			continue
+		}
+		newIDs[docID] = len(docs)
+		idx.ids[doc.id] = len(docs)
+		docs = append(docs, doc)
+	}
+
+	for fieldID, lengths := range idx.lengths {
+		compacted := make([]int, 0, len(docs))
+		for docID, length := range lengths {
+			if idx.docs[docID] != nil {
+				compacted = append(compacted, length)
+			}
+		}
+		idx.lengths[fieldID] = compacted
+	}
+	for _, postings := range idx.terms {
+		for _, list := range postings.fields {
+			if list == nil {
+				continue
+			}
+			for i, docID := range list.docIDs {
+				list.docIDs[i] = newIDs[docID]
+			}
+		}
+	}
+	idx.docs = docs
+}
+
+// fieldID returns the ID of a field, registering new fields.
+func (idx *Index) fieldID(name string) int {
+	if fieldID, exists := idx.fieldIDs[name]; exists {
+		return fieldID
+	}
+	fieldID := len(idx.fieldNames)
+	idx.fieldIDs[name] = fieldID
+	idx.fieldNames = append(idx.fieldNames, name)
+	idx.lengths = append(idx.lengths, make([]int, len(idx.docs)))
+	idx.totalLengths = append(idx.totalLengths, 0)
+	return fieldID
+}
+
+// Len returns the number of documents in the index.
+func (idx *Index) Len() int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return len(idx.ids)
+}
+
+// Documents returns every document in the index, in the order they were
+// added, with a zero score.
+func (idx *Index) Documents() []Result {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	results := make([]Result, 0, len(idx.ids))
+	for _, doc := range idx.docs {
+		if doc != nil {
+			results = append(results, idx.result(doc, 0))
+		}
+	}
+	return results
+}
+
+func (idx *Index) analyze(text string) []string {
+	return idx.Analyzer.Terms(text)
+}
+
+func (idx *Index) analyzeFields(fields map[string]string) map[string][]Token {
+	analyzed := make(map[string][]Token, len(fields))
+	for name, text := range fields {
+		analyzed[name] = idx.Analyzer.Analyze(text)
+	}
+	return analyzed
+}
+
+// result returns the search result for a document.
+func (idx *Index) result(doc *document, score float64) Result {
+	r := Result{ID: doc.id, Fields: make(map[string]string, len(doc.texts)), Score: score}
+	for fieldID, text := range doc.texts {
+		if text != "" {
+			r.Fields[idx.fieldNames[fieldID]] = text
+		}
+	}
+	r.Text = r.Fields[DefaultField]
+	return r
+}
+
+// list returns the postings of the term in a field, creating them.
+func (p *termPostings) list(fieldID int) *postingList {
+	for len(p.fields) <= fieldID {
+		p.fields = append(p.fields, nil)
+	}
+	if p.fields[fieldID] == nil {
+		p.fields[fieldID] = &postingList{}
+	}
+	return p.fields[fieldID]
+}
+
+// postingList holds the documents containing a term, ordered by docID, and
+// the positions of the term in them. Documents are added with increasing
+// docIDs, so adding is an append.
+type postingList struct {
+	docIDs    []int
+	termFreqs []int
+	// positions holds the positions in every document in docID order, and
+	// offsets[i] is where those of docIDs[i] start.
+	positions []int32
+	offsets   []int32
+	// maxTermFreq bounds the term frequencies in the list. It is not lowered
+	// when documents are removed, which keeps it a valid upper bound.
+	maxTermFreq int
+}
+
+func (l *postingList) add(docID int, positions []int32) {
+	l.docIDs = append(l.docIDs, docID)
+	l.termFreqs = append(l.termFreqs, len(positions))
+	l.offsets = append(l.offsets, int32(len(l.positions)))
+	l.positions = append(l.positions, positions...)
+	l.maxTermFreq = max(l.maxTermFreq, len(positions))
+}
+
+func (l *postingList) remove(docID int) {
+	i, found := slices.BinarySearch(l.docIDs, docID)
+	if !found {
+		return
+	}
+	termFreq := l.termFreqs[i]
+	l.positions = slices.Delete(l.positions, int(l.offsets[i]), int(l.offsets[i])+termFreq)
+	l.docIDs = slices.Delete(l.docIDs, i, i+1)
+	l.termFreqs = slices.Delete(l.termFreqs, i, i+1)
+	l.offsets = slices.Delete(l.offsets, i, i+1)
+	for j := i; j < len(l.offsets); j++ {
+		l.offsets[j] -= int32(termFreq)
+	}
+}
+
+// find returns the index of docID in the list, or -1.
+func (l *postingList) find(docID int) int {
+	i, found := slices.BinarySearch(l.docIDs, docID)
+	if !found {
+		return -1
+	}
+	return i
+}
+
+// positionsAt returns the positions of the term in docIDs[i], in order.
+func (l *postingList) positionsAt(i int) []int32 {
+	return l.positions[l.offsets[i] : int(l.offsets[i])+l.termFreqs[i]]
+}
@@ -0,0 +1,353 @@
+package bm25
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestDocuments(t *testing.T) {
+	index := NewIndex()
+	for _, id := range []string{"a", "b", "c"} {
+		if err := index.Add(id, "text of "+id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := index.Delete("b"); err != nil {
+		t.Fatal(err)
+	}
+	if err := index.Add("b", "new text of b"); err != nil {
+		t.Fatal(err)
+	}
+
+	docs := index.Documents()
+	want := []Result{{ID: "a", Text: "text of a"}, {ID: "c", Text: "text of c"}, {ID: "b", Text: "new text of b"}}
+	if len(docs) != len(want) {
+		t.Fatalf("got %d documents, want %d", len(docs), len(want))
+	}
+	for i, doc := range docs {
+		if doc.ID != want[i].ID || doc.Text != want[i].Text || doc.Score != 0 {
+			t.Errorf("document %d is %+v, want %+v", i, doc, want[i])
+		}
+	}
+}
+
+// checkConsistent recomputes the document count, field lengths and postings
+// from the stored documents and compares them with the index.
+func checkConsistent(t *testing.T, idx *Index) {
+	t.Helper()
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	type posting struct{ docID, termFreq int }
+	postings := make(map[string]map[int][]posting) // term -> fieldID -> postings
+	docFreqs := make(map[string]int)
+	totals := make([]int, len(idx.fieldNames))
+	live := 0
+
+	for docID, doc := range idx.docs {
+		for fieldID := range idx.lengths {
+			if len(idx.lengths[fieldID]) != len(idx.docs) {
+				t.Fatalf("field %d has %d lengths for %d documents", fieldID, len(idx.lengths[fieldID]), len(idx.docs))
+			}
+			if doc == nil && idx.lengths[fieldID][docID] != 0 {
+				t.Errorf("deleted document %d has length %d in field %d", docID, idx.lengths[fieldID][docID], fieldID)
+			}
+		}
+		if doc == nil {
+			continue
+		}
+		live++
+		if idx.ids[doc.id] != docID {
+			t.Errorf("ID %q maps to %d, want %d", doc.id, idx.ids[doc.id], docID)
+		}
+
+		inDoc := make(map[string]bool)
+		for fieldID, text := range doc.texts {
+			tokens := idx.Analyzer.Analyze(text)
+			if idx.lengths[fieldID][docID] != len(tokens) {
+				t.Errorf("document %q has length %d in field %d, want %d", doc.id, idx.lengths[fieldID][docID], fieldID, len(tokens))
+			}
+			totals[fieldID] += len(tokens)
+
+			termFreqs := make(map[string]int)
+			for _, token := range tokens {
+				termFreqs[token.Term]++
+			}
+			for term, termFreq := range termFreqs {
+				if postings[term] == nil {
+					postings[term] = make(map[int][]posting)
+				}
+				postings[term][fieldID] = append(postings[term][fieldID], posting{docID, termFreq})
+				if !inDoc[term] {
+					inDoc[term] = true
+					docFreqs[term]++
+				}
+			}
+		}
+	}
+
+	if len(idx.ids) != live {
+		t.Errorf("got %d IDs for %d documents", len(idx.ids), live)
+	}
+	if !reflect.DeepEqual(idx.totalLengths, totals) {
+		t.Errorf("got total lengths %v, want %v", idx.totalLengths, totals)
+	}
+	if len(idx.terms) != len(postings) {
+		t.Errorf("got %d terms, want %d", len(idx.terms), len(postings))
+	}
+	for term, want := range postings {
+		got, exists := idx.terms[term]
+		if !exists {
+			t.Errorf("term %q is missing", term)
+			continue
+		}
+		if got.docFreq != docFreqs[term] {
+			t.Errorf("term %q has document frequency %d, want %d", term, got.docFreq, docFreqs[term])
+		}
+		for fieldID, list := range got.fields {
+			var have []posting
+			if list != nil {
+				for i, docID := range list.docIDs {
+					have = append(have, posting{docID, list.termFreqs[i]})
+				}
+			}
+			if !reflect.DeepEqual(have, want[fieldID]) {
+				t.Errorf("term %q has postings %v in field %d, want %v", term, have, fieldID, want[fieldID])
+			}
+		}
+	}
+}
+
+// checkSameAsRebuilt checks that the index ranks documents exactly like an
+// index built from scratch with its current documents.
+func checkSameAsRebuilt(t *testing.T, idx *Index, queries []string) {
+	t.Helper()
+	rebuilt := NewIndex()
+	for _, doc := range idx.Documents() {
+		if err := rebuilt.AddFields(doc.ID, doc.Fields); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, query := range queries {
+		got, want := idx.Search(query, 10), rebuilt.Search(query, 10)
+		if !sameResults(got, want) {
+			t.Errorf("%q: got %v, want %v as rebuilt", query, summary(got), summary(want))
+		}
+	}
+}
+
+func TestUpdateDelete(t *testing.T) {
+	index := NewIndex()
+	queries := []string{"quick fox", "brown bear", "dog days", "news", "market"}
+	steps := []struct {
+		name string
+		run  func() error
+	}{
+		{"add a", func() error { return index.Add("a", "the quick brown fox") }},
+		{"add b", func() error { return index.Add("b", "the lazy dog") }},
+		{"add c", func() error {
+			return index.AddFields("c", map[string]string{"title": "fox news", "content": "quick quick fox"})
+		}},
+		{"update a", func() error { return index.Update("a", "a slow brown bear") }},
+		{"delete b", func() error { return index.Delete("b") }},
+		{"add b again", func() error { return index.Add("b", "dog days") }},
+		{"update c", func() error { return index.UpdateFields("c", map[string]string{"content": "bear market"}) }},
+		{"delete a", func() error { return index.Delete("a") }},
+	}
+	for _, step := range steps {
+		if err := step.run(); err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		checkConsistent(t, index)
+		checkSameAsRebuilt(t, index, queries)
+		if t.Failed() {
+			t.Fatalf("inconsistent after %s", step.name)
+		}
+	}
+
+	if got := index.Len(); got != 2 {
+		t.Errorf("got %d documents, want 2", got)
+	}
+	for _, term := range []string{"fox", "quick", "news", "brown", "lazy"} {
+		if _, exists := index.terms[term]; exists {
+			t.Errorf("term %q of removed text is still indexed", term)
+		}
+	}
+	s := index.scorer()
+	if got := s.avgLengths[index.fieldIDs[DefaultField]]; got != 2 {
+		t.Errorf("got average content length %v, want 2", got)
+	}
+	if got := s.avgLengths[index.fieldIDs["title"]]; got != 0 {
+		t.Errorf("got average title length %v, want 0", got)
+	}
+
+	if err := index.Add("b", "duplicate"); !errors.Is(err, ErrDuplicate) {
+		t.Errorf("got error %v adding a duplicate, want %v", err, ErrDuplicate)
+	}
+	if err := index.Update("a", "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("got error %v updating a deleted document, want %v", err, ErrNotFound)
+	}
+	if err := index.Delete("a"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("got error %v deleting a deleted document, want %v", err, ErrNotFound)
+	}
+}
+
+func TestCompact(t *testing.T) {
+	index, queries := zipfCorpus(t, 300, 20, 2)
+	for i := 0; i < 300; i += 3 {
+		if err := index.Delete(strconv.Itoa(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 1; i < 300; i += 9 {
+		if err := index.Update(strconv.Itoa(i), "updated"+queries[i%len(queries)]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	before := make([][]Result, len(queries))
+	for i, query := range queries {
+		before[i] = index.Search(query, 10)
+	}
+
+	index.Compact()
+	checkConsistent(t, index)
+	if len(index.docs) != index.Len() {
+		t.Errorf("got %d document slots for %d documents", len(index.docs), index.Len())
+	}
+	for i, query := range queries {
+		if got := index.Search(query, 10); !sameResults(got, before[i]) {
+			t.Errorf("%q: got %v after compacting, want %v", query, summary(got), summary(before[i]))
+		}
+	}
+
+	// The compacted index can still be changed
+	if err := index.Delete("1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := index.Add("0", "w1 w2 w3"); err != nil {
+		t.Fatal(err)
+	}
+	checkConsistent(t, index)
+	checkSameAsRebuilt(t, index, queries)
+}
+
+func TestConcurrentReadWrite(t *testing.T) {
+	const (
+		writers = 4
+		docs    = 5 // per writer
+		rounds  = 10
+	)
+	words := []string{"spider", "pig", "barn", "web", "rat", "goose", "fair", "egg"}
+	text := func(id string, round int) string {
+		return fmt.Sprintf("%s %s %s %s", id, words[round%len(words)], words[(round*3+1)%len(words)], strings.Repeat("barn ", round%4))
+	}
+
+	index := NewIndex()
+	for w := 0; w < writers; w++ {
+		for d := 0; d < docs; d++ {
+			id := fmt.Sprintf("doc%dx%d", w, d)
+			if err := index.Add(id, text(id, 0)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	var writing sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		writing.Add(1)
+		go func() {
+			defer writing.Done()
+			rng := rand.New(rand.NewSource(int64(w)))
+			deleted := make(map[string]bool)
+			for round := 1; round <= rounds; round++ {
+				for d := 0; d < docs; d++ {
+					// Each writer owns its documents, so the operations cannot fail
+					id := fmt.Sprintf("doc%dx%d", w, d)
+					var err error
+					switch {
+					case deleted[id]:
+						err = index.Add(id, text(id, round))
+						deleted[id] = false
+					case rng.Intn(4) == 0:
+						err = index.Delete(id)
+						deleted[id] = true
+					default:
+						err = index.Update(id, text(id, round))
+					}
+					if err != nil {
+						t.Error(err)
+						return
+					}
+					// Let the readers in between changes
+					runtime.Gosched()
+				}
+				if round%5 == 0 {
+					index.Compact()
+				}
+			}
+		}()
+	}
+
+	// Readers run until the writers are done, yielding between calls so
+	// that they interleave with the changes
+	done := make(chan struct{})
+	var reading sync.WaitGroup
+	for r := 0; r < 4; r++ {
+		query := words[r%len(words)] + " " + words[(r+3)%len(words)]
+		reads := []func() bool{
+			func() bool {
+				for _, result := range index.Search(query, 5) {
+					// A document is seen either before or after a change
+					if !strings.HasPrefix(result.Text, result.ID+" ") {
+						t.Errorf("got result %+v", result)
+						return false
+					}
+				}
+				return true
+			},
+			func() bool {
+				for _, doc := range index.Documents() {
+					if !strings.HasPrefix(doc.Text, doc.ID+" ") {
+						t.Errorf("got document %+v", doc)
+						return false
+					}
+				}
+				return true
+			},
+			func() bool {
+				index.Score(query, "doc0x0")
+				return index.Len() <= writers*docs
+			},
+		}
+
+		reading.Add(1)
+		go func() {
+			defer reading.Done()
+			for i := 0; ; i++ {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				if !reads[i%len(reads)]() {
+					return
+				}
+				runtime.Gosched()
+			}
+		}()
+	}
+
+	writing.Wait()
+	close(done)
+	reading.Wait()
+
+	checkConsistent(t, index)
+	checkSameAsRebuilt(t, index, []string{"spider pig", "barn", "web rat goose", "doc1x3 fair"})
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/jacygao/ai/bm25"
+)
+
+// jsonDocument is a document in a .json file, which holds either one
+// document or an array of them. Its "text" and other string fields, or
+// arrays of strings such as tags, are indexed as fields.
+type jsonDocument struct {
+	ID     string
+	Fields map[string]string
+}
+
+// runBuild indexes the .txt and .json files under a directory and saves the
+// index. A .txt file is one document with its path as the ID.
+func runBuild(args []string) error {
+	flags := flag.NewFlagSet("build", flag.ExitOnError)
+	dir := flags.String("dir", ".", "Directory of .txt and .json documents to index")
+	out := flags.String("out", "index.bm25", "Path of the index file to write")
+	newAnalyzer := registerAnalyzerFlags(flags)
+	applyScoring := registerScoringFlags(flags)
+	flags.Parse(args)
+
+	analyzer, err := newAnalyzer()
+	if err != nil {
+		return err
+	}
+	index := bm25.NewIndex()
+	index.Analyzer = analyzer
+	if err := applyScoring(index); err != nil {
+		return err
+	}
+
+	err = filepath.WalkDir(*dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		id, err := filepath.Rel(*dir, path)
+		if err != nil {
+			return err
+		}
+		id = filepath.ToSlash(id)
+
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".txt":
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			return index.Add(id, string(data))
+		case ".json":
+			docs, err := readJSONDocuments(path)
+			if err != nil {
+				return err
+			}
+			for i, doc := range docs {
+				if doc.ID == "" {
+					doc.ID = fmt.Sprintf("%s#%d", id, i)
+				}
+				if err := index.AddFields(doc.ID, doc.Fields); err != nil {
+					return fmt.Errorf("%s: %w", path, err)
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("error indexing %s: %w", *dir, err)
+	}
+
+	if err := index.Save(*out); err != nil {
+		return err
+	}
+	fmt.Printf("Indexed %d documents into %s\n", index.Len(), *out)
+	return nil
+}
+
+func readJSONDocuments(path string) ([]jsonDocument, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var objects []map[string]any
+	if strings.HasPrefix(strings.TrimSpace(string(data)), "[") {
+		err = json.Unmarshal(data, &objects)
+	} else {
+		var object map[string]any
+		err = json.Unmarshal(data, &object)
+		objects = append(objects, object)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error decoding %s: %w", path, err)
+	}
+
+	docs := make([]jsonDocument, 0, len(objects))
+	for _, object := range objects {
+		doc := jsonDocument{Fields: make(map[string]string)}
+		for name, value := range object {
+			if name == "id" {
+				doc.ID = fmt.Sprint(value)
+				continue
+			}
+			if name == "text" {
+				name = bm25.DefaultField
+			}
+			switch value := value.(type) {
+			case string:
+				doc.Fields[name] = value
+			case []any:
+				var values []string
+				for _, v := range value {
+					if s, ok := v.(string); ok {
+						values = append(values, s)
+					}
+				}
+				doc.Fields[name] = strings.Join(values, ", ")
+			}
+		}
+		docs = append(docs, doc)
+	}
+	return docs, nil
+}
+
+// registerAnalyzerFlags registers the text analysis flags and returns a
+// function building the analyzer they describe once flags are parsed.
+func registerAnalyzerFlags(flags *flag.FlagSet) func() (*bm25.Analyzer, error) {
+	stopwordFiles := flags.String("stopwords", "", "Comma separated stopword files, one word per line, replacing the built-in English list")
+	stem := flags.Bool("stem", true, "Reduce words to their stems so different forms of a word match")
+	shingles := flags.Int("shingles", 1, "Also index runs of up to this many adjacent words, favouring documents matching the query's word order")
+
+	return func() (*bm25.Analyzer, error) {
+		stopwords := bm25.EnglishStopwords()
+		if *stopwordFiles != "" {
+			var err error
+			stopwords, err = bm25.LoadStopwords(strings.Split(*stopwordFiles, ",")...)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		return bm25.AnalyzerConfig{
+			Lowercase: true,
+			Stopwords: bm25.SortedWords(stopwords),
+			Stem:      *stem,
+			Shingles:  *shingles,
+		}.Build()
+	}
+}
+
+// registerScoringFlags registers the scoring flags and returns a function
+// applying the ones that were set to an index.
+func registerScoringFlags(flags *flag.FlagSet) func(index *bm25.Index) error {
+	scoring := flags.String("scoring", "okapi", "BM25 variant: okapi, bm25+ or bm25l")
+	k1 := flags.Float64("k1", bm25.DefaultK1, "BM25 term frequency saturation")
+	b := flags.Float64("b", bm25.DefaultB, "BM25 document length normalization")
+	nonNegativeIDF := flags.Bool("non-negative-idf", false, "Keep terms in most documents from lowering scores")
+	fieldWeights := flags.String("field-weights", "", "Comma separated field=weight pairs for multi-field documents, e.g. title=2,tags=1.5")
+
+	return func(index *bm25.Index) error {
+		var err error
+		flags.Visit(func(f *flag.Flag) {
+			if err != nil {
+				return
+			}
+			switch f.Name {
+			case "scoring":
+				index.Scoring, err = bm25.ParseScoring(*scoring)
+			case "k1":
+				index.K1 = *k1
+			case "b":
+				index.B = *b
+			case "non-negative-idf":
+				index.NonNegativeIDF = *nonNegativeIDF
+			case "field-weights":
+				index.Fields, err = parseFieldWeights(*fieldWeights, *b)
+			}
+		})
+		return err
+	}
+}
+
+// parseFieldWeights parses field=weight pairs. Every field uses the same b.
+func parseFieldWeights(s string, b float64) (map[string]bm25.Field, error) {
+	fields := make(map[string]bm25.Field)
+	for _, pair := range strings.Split(s, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid field weight %q, want field=weight", pair)
+		}
+		weight, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid field weight %q: %w", pair, err)
+		}
+		fields[name] = bm25.Field{Weight: weight, B: b}
+	}
+	return fields, nil
+}
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// usage example:
+// go run ./bm25/cmd build -dir ./docs -out docs.bm25
+// go run ./rag -backend bm25 -index docs.bm25
+func main() {
+	if len(os.Args) < 2 || os.Args[1] != "build" {
+		fmt.Fprintln(os.Stderr, "Usage: cmd build -dir ./docs -out docs.bm25")
+		fmt.Fprintln(os.Stderr, "Chat over an index with: go run ./rag -backend bm25 -index docs.bm25")
+		os.Exit(2)
+	}
+	if err := runBuild(os.Args[2:]); err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,269 @@
+package bm25
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/jacygao/ai/internal/indexfile"
+)
+
+// Index files are framed by the indexfile package with the magic
+// "BM25IDX\x00". DocIDs are delta encoded within a postings list. The body is
+//
+//	header    JSON encoded fileHeader: scoring settings, analyzer and field names
+//	docs      count, then per document its ID, field count and text and length of each field
+//	postings  count, then per term the term, its document frequency, the
+//	          number of fields and per field the fieldID, document count,
+//	          docID deltas, term frequencies and per document the position deltas
+//
+// Version 2 added fields and scoring variants, version 3 positions. Older
+// files are not read.
+const (
+	fileMagic   = "BM25IDX\x00"
+	fileVersion = 3
+)
+
+// fileHeader holds the settings of a saved index.
+type fileHeader struct {
+	K1             float64          `json:"k1"`
+	B              float64          `json:"b"`
+	Scoring        string           `json:"scoring"`
+	Delta          float64          `json:"delta,omitempty"`
+	NonNegativeIDF bool             `json:"non_negative_idf,omitempty"`
+	Fields         map[string]Field `json:"fields,omitempty"`
+	MaxScore       bool             `json:"max_score"`
+	Analyzer       AnalyzerConfig   `json:"analyzer"`
+	FieldNames     []string         `json:"field_names"`
+}
+
+var (
+	ErrCorrupt     = errors.New("bm25: index file is corrupt")
+	ErrVersion     = errors.New("bm25: unsupported index file version")
+	ErrNotSaveable = errors.New("bm25: index analyzer was not built from an AnalyzerConfig")
+)
+
+// Save writes the index to path. The file is written next to it first and
+// renamed, so readers never see a partial index.
+func (idx *Index) Save(path string) error {
+	return indexfile.Save(path, idx)
+}
+
+// Load reads an index saved with Save.
+func Load(path string) (*Index, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error loading index: %w", err)
+	}
+	return decodeIndex(data)
+}
+
+// WriteTo encodes the index. Deleted documents are left out, so the docIDs
+// of a loaded index are dense again.
+func (idx *Index) WriteTo(w io.Writer) (int64, error) {
+	config, ok := idx.Analyzer.Config()
+	if !ok {
+		return 0, ErrNotSaveable
+	}
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	header, err := json.Marshal(fileHeader{
+		K1:             idx.K1,
+		B:              idx.B,
+		Scoring:        idx.Scoring.String(),
+		Delta:          idx.Delta,
+		NonNegativeIDF: idx.NonNegativeIDF,
+		Fields:         idx.Fields,
+		MaxScore:       idx.MaxScore,
+		Analyzer:       config,
+		FieldNames:     idx.fieldNames,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("error encoding index header: %w", err)
+	}
+
+	e := indexfile.NewEncoder(fileMagic, fileVersion)
+	e.Bytes(header)
+
+	newIDs := make([]int, len(idx.docs))
+	next := 0
+	e.Uvarint(len(idx.ids))
+	for docID, doc := range idx.docs {
+		if doc == nil {
+			continue
+		}
+		newIDs[docID] = next
+		next++
+		e.Bytes([]byte(doc.id))
+		e.Uvarint(len(doc.texts))
+		for fieldID, text := range doc.texts {
+			e.Bytes([]byte(text))
+			e.Uvarint(idx.lengths[fieldID][docID])
+		}
+	}
+
+	// Terms are sorted so the same index always encodes to the same bytes
+	terms := make([]string, 0, len(idx.terms))
+	for term := range idx.terms {
+		terms = append(terms, term)
+	}
+	sort.Strings(terms)
+
+	e.Uvarint(len(terms))
+	for _, term := range terms {
+		postings := idx.terms[term]
+		e.Bytes([]byte(term))
+		e.Uvarint(postings.docFreq)
+
+		fieldCount := 0
+		for _, list := range postings.fields {
+			if list != nil && len(list.docIDs) > 0 {
+				fieldCount++
+			}
+		}
+		e.Uvarint(fieldCount)
+		for fieldID, list := range postings.fields {
+			if list == nil || len(list.docIDs) == 0 {
+				continue
+			}
+			e.Uvarint(fieldID)
+			e.Uvarint(len(list.docIDs))
+			previous := 0
+			for _, docID := range list.docIDs {
+				e.Uvarint(newIDs[docID] - previous)
+				previous = newIDs[docID]
+			}
+			for _, termFreq := range list.termFreqs {
+				e.Uvarint(termFreq)
+			}
+			for i := range list.docIDs {
+				previous := int32(0)
+				for _, position := range list.positionsAt(i) {
+					e.Uvarint(int(position - previous))
+					previous = position
+				}
+			}
+		}
+	}
+
+	return e.WriteTo(w)
+}
+
+// ReadIndex decodes an index encoded with WriteTo.
+func ReadIndex(r io.Reader) (*Index, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("error reading index: %w", err)
+	}
+	return decodeIndex(data)
+}
+
+func decodeIndex(data []byte) (*Index, error) {
+	d, err := indexfile.NewDecoder(data, fileMagic, fileVersion)
+	var versionErr *indexfile.VersionError
+	if errors.As(err, &versionErr) {
+		return nil, fmt.Errorf("%w: %d", ErrVersion, versionErr.Version)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCorrupt, err)
+	}
+
+	var header fileHeader
+	if err := json.Unmarshal(d.Bytes(), &header); d.Err() == nil && err != nil {
+		return nil, fmt.Errorf("%w: header: %v", ErrCorrupt, err)
+	}
+	if d.Err() != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCorrupt, d.Err())
+	}
+
+	idx := NewIndex()
+	idx.K1 = header.K1
+	idx.B = header.B
+	idx.Delta = header.Delta
+	idx.NonNegativeIDF = header.NonNegativeIDF
+	idx.Fields = header.Fields
+	idx.MaxScore = header.MaxScore
+	scoring, err := ParseScoring(header.Scoring)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCorrupt, err)
+	}
+	idx.Scoring = scoring
+	analyzer, err := header.Analyzer.Build()
+	if err != nil {
+		return nil, fmt.Errorf("%w: analyzer: %v", ErrCorrupt, err)
+	}
+	idx.Analyzer = analyzer
+	for _, name := range header.FieldNames {
+		idx.fieldID(name)
+	}
+
+	docCount := d.Uvarint()
+	for docID := 0; docID < docCount && d.Err() == nil; docID++ {
+		doc := &document{id: string(d.Bytes())}
+		fieldCount := d.Uvarint()
+		if fieldCount > len(idx.fieldNames) {
+			d.Fail(fmt.Errorf("document %q has %d fields", doc.id, fieldCount))
+			break
+		}
+		for fieldID := range idx.lengths {
+			idx.lengths[fieldID] = append(idx.lengths[fieldID], 0)
+		}
+		for fieldID := 0; fieldID < fieldCount && d.Err() == nil; fieldID++ {
+			doc.texts = append(doc.texts, string(d.Bytes()))
+			idx.lengths[fieldID][docID] = d.Uvarint()
+			idx.totalLengths[fieldID] += idx.lengths[fieldID][docID]
+		}
+		idx.docs = append(idx.docs, doc)
+		idx.ids[doc.id] = docID
+	}
+
+	termCount := d.Uvarint()
+	for i := 0; i < termCount && d.Err() == nil; i++ {
+		term := string(d.Bytes())
+		postings := &termPostings{docFreq: d.Uvarint()}
+		fieldCount := d.Uvarint()
+		for j := 0; j < fieldCount && d.Err() == nil; j++ {
+			fieldID := d.Uvarint()
+			if fieldID >= len(idx.fieldNames) {
+				d.Fail(fmt.Errorf("fieldID %d out of range", fieldID))
+				break
+			}
+			list := postings.list(fieldID)
+			n := d.Uvarint()
+			list.docIDs = make([]int, 0, min(n, d.Len()))
+			list.termFreqs = make([]int, 0, min(n, d.Len()))
+			docID := 0
+			for k := 0; k < n && d.Err() == nil; k++ {
+				docID += d.Uvarint()
+				if docID >= len(idx.docs) {
+					d.Fail(fmt.Errorf("docID %d out of range", docID))
+				}
+				list.docIDs = append(list.docIDs, docID)
+			}
+			for k := 0; k < n && d.Err() == nil; k++ {
+				termFreq := d.Uvarint()
+				list.termFreqs = append(list.termFreqs, termFreq)
+				list.maxTermFreq = max(list.maxTermFreq, termFreq)
+			}
+			for k := 0; k < n && d.Err() == nil; k++ {
+				list.offsets = append(list.offsets, int32(len(list.positions)))
+				position := int32(0)
+				for p := 0; p < list.termFreqs[k] && d.Err() == nil; p++ {
+					position += int32(d.Uvarint())
+					list.positions = append(list.positions, position)
+				}
+			}
+		}
+		idx.terms[term] = postings
+	}
+
+	if err := d.Finish(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCorrupt, err)
+	}
+	return idx, nil
+}
@@ -0,0 +1,80 @@
+package bm25
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestSaveLoad(t *testing.T) {
+	index, queries := zipfCorpus(t, 500, 20, 1)
+	index.Scoring = BM25Plus
+	index.MaxScore = true
+	if err := index.AddFields("title", map[string]string{"title": "the quick brown fox", "body": "jumps over the lazy dog"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"3", "42", "250"} {
+		if err := index.Delete(id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	queries = append(queries, `"quick brown"`, "title:fox OR lazy")
+
+	path := filepath.Join(t.TempDir(), "index.bm25")
+	if err := index.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if loaded.Scoring != index.Scoring || !loaded.MaxScore || loaded.Len() != index.Len() {
+		t.Errorf("got %s MaxScore=%v with %d documents, want %s MaxScore=true with %d documents",
+			loaded.Scoring, loaded.MaxScore, loaded.Len(), index.Scoring, index.Len())
+	}
+	for _, query := range queries {
+		got, want := loaded.Search(query, 10), index.Search(query, 10)
+		if !sameResults(got, want) {
+			t.Fatalf("results for %q differ:\ngot  %v\nwant %v", query, summary(got), summary(want))
+		}
+	}
+
+	if _, err := loaded.WriteTo(&bytes.Buffer{}); err != nil {
+		t.Errorf("got error %v saving a loaded index", err)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	index, _ := zipfCorpus(t, 20, 0, 1)
+	var buf bytes.Buffer
+	if _, err := index.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	flipped := bytes.Clone(data)
+	flipped[len(flipped)/2] ^= 0xff
+	older := bytes.Clone(data)
+	older[len(fileMagic)]--
+
+	tests := []struct {
+		name string
+		data []byte
+		err  error
+	}{
+		{name: "empty", data: nil, err: ErrCorrupt},
+		{name: "other magic", data: append([]byte("HNSWIDX\x00"), data[len(fileMagic):]...), err: ErrCorrupt},
+		{name: "other version", data: older, err: ErrVersion},
+		{name: "flipped byte", data: flipped, err: ErrCorrupt},
+		{name: "truncated", data: data[:len(data)-10], err: ErrCorrupt},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := ReadIndex(bytes.NewReader(test.data)); !errors.Is(err, test.err) {
+				t.Errorf("got error %v, want %v", err, test.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,264 @@
+package bm25
+
+import "strings"
+
+// PorterStem returns the stem of a lower case English word using the Porter
+// algorithm (M.F. Porter, "An algorithm for suffix stripping", 1980), with
+// the revisions of the reference implementation. A possessive 's is removed
+// first. Words that are not plain ASCII letters are returned unchanged.
+func PorterStem(word string) string {
+	word = strings.TrimSuffix(word, "'s")
+	word = strings.TrimSuffix(word, "’s")
+	if len(word) <= 2 {
+		return word
+	}
+	for i := 0; i < len(word); i++ {
+		if word[i] < 'a' || word[i] > 'z' {
+			return word
+		}
+	}
+
+	s := &stemmer{b: []byte(word)}
+	s.step1ab()
+	if len(s.b) > 1 {
+		s.step1c()
+		s.step2()
+		s.step3()
+		s.step4()
+		s.step5()
+	}
+	return string(s.b)
+}
+
+// stemmer holds the word being stemmed. j marks the end of the stem when
+// a suffix has been matched by ends.
+type stemmer struct {
+	b []byte
+	j int
+}
+
+func (s *stemmer) k() int {
+	return len(s.b) - 1
+}
+
+// cons reports whether b[i] is a consonant. Y is a consonant at the start
+// of a word or after a vowel.
+func (s *stemmer) cons(i int) bool {
+	switch s.b[i] {
+	case 'a', 'e', 'i', 'o', 'u':
+		return false
+	case 'y':
+		return i == 0 || !s.cons(i-1)
+	}
+	return true
+}
+
+// m measures the number of consonant sequences in b[0..j]. With c a
+// consonant sequence and v a vowel sequence, [c](vc){m}[v] gives m.
+func (s *stemmer) m() int {
+	n := 0
+	i := 0
+	for {
+		if i > s.j {
+			return n
+		}
+		if !s.cons(i) {
+			break
+		}
+		i++
+	}
+	i++
+	for {
+		for {
+			if i > s.j {
+				return n
+			}
+			if s.cons(i) {
+				break
+			}
+			i++
+		}
+		i++
+		n++
+		for {
+			if i > s.j {
+				return n
+			}
+			if !s.cons(i) {
+				break
+			}
+			i++
+		}
+		i++
+	}
+}
+
+// vowelInStem reports whether b[0..j] contains a vowel.
+func (s *stemmer) vowelInStem() bool {
+	for i := 0; i <= s.j; i++ {
+		if !s.cons(i) {
+			return true
+		}
+	}
+	return false
+}
+
+// doubleCons reports whether b[i-1..i] is a double consonant.
+func (s *stemmer) doubleCons(i int) bool {
+	return i >= 1 && s.b[i] == s.b[i-1] && s.cons(i)
+}
+
+// cvc reports whether b[i-2..i] is consonant, vowel, consonant and the last
+// consonant is not w, x or y. It restores an e in words like hop(e) and fil(e).
+func (s *stemmer) cvc(i int) bool {
+	if i < 2 || !s.cons(i) || s.cons(i-1) || !s.cons(i-2) {
+		return false
+	}
+	switch s.b[i] {
+	case 'w', 'x', 'y':
+		return false
+	}
+	return true
+}
+
+// ends reports whether the word ends with suffix, and if so sets j to the
+// end of the stem before it.
+func (s *stemmer) ends(suffix string) bool {
+	if len(suffix) > len(s.b) || string(s.b[len(s.b)-len(suffix):]) != suffix {
+		return false
+	}
+	s.j = len(s.b) - len(suffix) - 1
+	return true
+}
+
+// setTo replaces b[j+1..] with suffix.
+func (s *stemmer) setTo(suffix string) {
+	s.b = append(s.b[:s.j+1], suffix...)
+}
+
+// replace replaces the matched suffix when the stem has a measure above 0.
+func (s *stemmer) replace(suffix string) {
+	if s.m() > 0 {
+		s.setTo(suffix)
+	}
+}
+
+// step1ab removes plurals and -ed or -ing, e.g. caresses -> caress,
+// ponies -> poni, agreed -> agree, hopping -> hop, filing -> file.
+func (s *stemmer) step1ab() {
+	if s.b[s.k()] == 's' {
+		if s.ends("sses") {
+			s.b = s.b[:len(s.b)-2]
+		} else if s.ends("ies") {
+			s.setTo("i")
+		} else if s.b[s.k()-1] != 's' {
+			s.b = s.b[:len(s.b)-1]
+		}
+	}
+
+	if s.ends("eed") {
+		if s.m() > 0 {
+			s.b = s.b[:len(s.b)-1]
+		}
+	} else if (s.ends("ed") || s.ends("ing")) && s.vowelInStem() {
+		s.b = s.b[:s.j+1]
+		if s.ends("at") {
+			s.setTo("ate")
+		} else if s.ends("bl") {
+			s.setTo("ble")
+		} else if s.ends("iz") {
+			s.setTo("ize")
+		} else if s.doubleCons(s.k()) {
+			switch s.b[s.k()] {
+			case 'l', 's', 'z':
+			default:
+				s.b = s.b[:len(s.b)-1]
+			}
+		} else {
+			s.j = s.k()
+			if s.m() == 1 && s.cvc(s.k()) {
+				s.setTo("e")
+			}
+		}
+	}
+}
+
+// step1c turns a terminal y into i when there is another vowel in the stem.
+func (s *stemmer) step1c() {
+	if s.ends("y") && s.vowelInStem() {
+		s.b[s.k()] = 'i'
+	}
+}
+
+// Suffixes in the order the reference implementation checks them. Only the
+// first suffix the word ends with is considered.
+var (
+	step2Suffixes = [][2]string{
+		{"ational", "ate"}, {"tional", "tion"}, {"enci", "ence"}, {"anci", "ance"},
+		{"izer", "ize"}, {"bli", "ble"}, {"alli", "al"}, {"entli", "ent"},
+		{"eli", "e"}, {"ousli", "ous"}, {"ization", "ize"}, {"ation", "ate"},
+		{"ator", "ate"}, {"alism", "al"}, {"iveness", "ive"}, {"fulness", "ful"},
+		{"ousness", "ous"}, {"aliti", "al"}, {"iviti", "ive"}, {"biliti", "ble"},
+		{"logi", "log"},
+	}
+	step3Suffixes = [][2]string{
+		{"icate", "ic"}, {"ative", ""}, {"alize", "al"}, {"iciti", "ic"},
+		{"ical", "ic"}, {"ful", ""}, {"ness", ""},
+	}
+	step4Suffixes = []string{
+		"al", "ance", "ence", "er", "ic", "able", "ible", "ant", "ement",
+		"ment", "ent", "ion", "ou", "ism", "ate", "iti", "ous", "ive", "ize",
+	}
+)
+
+// step2 maps double suffixes to single ones, e.g. -ization -> -ize.
+func (s *stemmer) step2() {
+	for _, suffix := range step2Suffixes {
+		if s.ends(suffix[0]) {
+			s.replace(suffix[1])
+			return
+		}
+	}
+}
+
+// step3 handles -ic-, -full, -ness etc.
+func (s *stemmer) step3() {
+	for _, suffix := range step3Suffixes {
+		if s.ends(suffix[0]) {
+			s.replace(suffix[1])
+			return
+		}
+	}
+}
+
+// step4 removes -ant, -ence etc. from stems with a measure above 1.
+func (s *stemmer) step4() {
+	for _, suffix := range step4Suffixes {
+		if !s.ends(suffix) {
+			continue
+		}
+		if suffix == "ion" && (s.j < 0 || (s.b[s.j] != 's' && s.b[s.j] != 't')) {
+			return
+		}
+		if s.m() > 1 {
+			s.b = s.b[:s.j+1]
+		}
+		return
+	}
+}
+
+// step5 removes a final -e and turns -ll into -l when the measure is above 1.
+func (s *stemmer) step5() {
+	s.j = s.k()
+	if s.b[s.k()] == 'e' {
+		a := s.m()
+		if a > 1 || (a == 1 && !s.cvc(s.k()-1)) {
+			s.b = s.b[:len(s.b)-1]
+			// Dropping a final vowel does not change the measure
+			s.j = s.k()
+		}
+	}
+	if s.b[s.k()] == 'l' && s.doubleCons(s.k()) && s.m() > 1 {
+		s.b = s.b[:len(s.b)-1]
+	}
+}
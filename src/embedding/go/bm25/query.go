@@ -0,0 +1,493 @@
+package bm25
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// Queries are parsed leniently: unbalanced quotes and parentheses are
+// closed at the end of the query instead of failing it.
+//
+//	software engineer        documents with either word, ranked by BM25
+//	"software engineer"      the words next to each other, in order
+//	+charlotte -lawyer       charlotte required, lawyer excluded
+//	NOT lawyer               same as -lawyer
+//	jacy AND charlotte       both required, AND binds the clauses next to it
+//	jacy OR matt             either, the default between clauses
+//	(jacy OR matt) +married  parentheses group clauses
+//	title:python             python in the title field
+//	title:"machine learning" a phrase in the title field
+//	title:(python OR go)     every clause of the group in the title field
+//
+// A word the analyzer splits into several terms, like "e-mail", matches any
+// of them unless it is quoted. Only terms outside excluded clauses add to the
+// score. A query without required or optional clauses matches nothing.
+
+// occur is how a clause has to match.
+type occur int
+
+const (
+	should occur = iota
+	must
+	mustNot
+)
+
+// queryNode is a *termQuery, *phraseQuery or *boolQuery.
+type queryNode interface{}
+
+// termQuery matches a term in a field, or in any field when field is empty.
+type termQuery struct {
+	field string
+	term  string
+}
+
+// phraseQuery matches the tokens at the same distances from each other as
+// in the query, within a single field.
+type phraseQuery struct {
+	field  string
+	tokens []Token
+}
+
+type boolQuery struct {
+	clauses []clause
+}
+
+type clause struct {
+	occur occur
+	node  queryNode
+}
+
+// fieldTerm is a term searched for in a field, or in any field.
+type fieldTerm struct {
+	field string
+	term  string
+}
+
+// parseQuery parses a query into a boolQuery, analyzing words and phrases
+// with the index analyzer.
+func (idx *Index) parseQuery(query string) *boolQuery {
+	p := &queryParser{input: []rune(query), analyzer: idx.Analyzer}
+	return p.parseGroup("", false)
+}
+
+// plain reports whether a query only has optional terms in any field, so
+// it can be searched as a bag of words.
+func (q *boolQuery) plain() bool {
+	for _, c := range q.clauses {
+		if c.occur != should {
+			return false
+		}
+		switch node := c.node.(type) {
+		case *termQuery:
+			if node.field != "" {
+				return false
+			}
+		case *boolQuery:
+			if !node.plain() {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// plainTerms returns the terms of a plain query. The words between
+// operators are analyzed together rather than one by one as the parser does,
+// so shingles spanning adjacent words are kept, but OR, parentheses and
+// quotes are not searched for and no shingle spans them.
+func (idx *Index) plainTerms(query string) []fieldTerm {
+	var terms []string
+	var run []string
+	flush := func() {
+		if len(run) > 0 {
+			terms = append(terms, idx.analyze(strings.Join(run, " "))...)
+			run = run[:0]
+		}
+	}
+
+	input := []rune(query)
+	quoted := false
+	for pos := 0; pos < len(input); {
+		switch r := input[pos]; {
+		case unicode.IsSpace(r):
+			pos++
+			continue
+		case r == '(' || r == ')' || r == '"':
+			flush()
+			if r == '"' {
+				quoted = !quoted
+			}
+			pos++
+			continue
+		}
+
+		end := pos
+		for end < len(input) && !isQueryDelimiter(input[end]) {
+			end++
+		}
+		word := string(input[pos:end])
+		pos = end
+		if !quoted && (word == "OR" || word == "||") {
+			flush()
+			continue
+		}
+		run = append(run, word)
+	}
+	flush()
+	return anyField(terms)
+}
+
+type queryParser struct {
+	input    []rune
+	pos      int
+	analyzer *Analyzer
+}
+
+func (p *queryParser) parseGroup(field string, nested bool) *boolQuery {
+	q := &boolQuery{}
+	and := false
+	pending := should
+
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.input) {
+			return q
+		}
+		if p.input[p.pos] == ')' {
+			p.pos++
+			if nested {
+				return q
+			}
+			continue
+		}
+
+		switch word := p.peekWord(); word {
+		case "AND", "&&":
+			p.pos += len(word)
+			and = true
+			continue
+		case "OR", "||":
+			p.pos += len(word)
+			continue
+		case "NOT":
+			p.pos += len(word)
+			pending = mustNot
+			continue
+		}
+
+		occur := pending
+		pending = should
+		switch p.input[p.pos] {
+		case '+':
+			occur = must
+			p.pos++
+		case '-':
+			occur = mustNot
+			p.pos++
+		}
+
+		node := p.parseClause(field)
+		if node == nil {
+			continue
+		}
+		if and {
+			if last := len(q.clauses) - 1; last >= 0 && q.clauses[last].occur == should {
+				q.clauses[last].occur = must
+			}
+			if occur == should {
+				occur = must
+			}
+			and = false
+		}
+		q.clauses = append(q.clauses, clause{occur: occur, node: node})
+	}
+}
+
+// parseClause parses a word, phrase or group, optionally prefixed by a
+// field name. It returns nil for clauses without terms, e.g. stopwords.
+func (p *queryParser) parseClause(field string) queryNode {
+	if name, ok := p.fieldPrefix(); ok {
+		field = name
+	}
+	if p.pos >= len(p.input) {
+		return nil
+	}
+
+	switch p.input[p.pos] {
+	case '(':
+		p.pos++
+		group := p.parseGroup(field, true)
+		if len(group.clauses) == 0 {
+			return nil
+		}
+		return group
+	case '"':
+		p.pos++
+		end := p.pos
+		for end < len(p.input) && p.input[end] != '"' {
+			end++
+		}
+		tokens := p.analyzer.Analyze(string(p.input[p.pos:end]))
+		p.pos = min(end+1, len(p.input))
+		if len(tokens) == 1 {
+			return &termQuery{field: field, term: tokens[0].Term}
+		}
+		if len(tokens) > 1 {
+			return &phraseQuery{field: field, tokens: tokens}
+		}
+		return nil
+	}
+
+	start := p.pos
+	for p.pos < len(p.input) && !isQueryDelimiter(p.input[p.pos]) {
+		p.pos++
+	}
+	tokens := p.analyzer.Analyze(string(p.input[start:p.pos]))
+	if len(tokens) == 1 {
+		return &termQuery{field: field, term: tokens[0].Term}
+	}
+	if len(tokens) > 1 {
+		q := &boolQuery{}
+		for _, token := range tokens {
+			q.clauses = append(q.clauses, clause{occur: should, node: &termQuery{field: field, term: token.Term}})
+		}
+		return q
+	}
+	return nil
+}
+
+// fieldPrefix consumes a field name followed by a colon.
+func (p *queryParser) fieldPrefix() (string, bool) {
+	end := p.pos
+	for end < len(p.input) && (unicode.IsLetter(p.input[end]) || unicode.IsDigit(p.input[end]) || p.input[end] == '_') {
+		end++
+	}
+	if end == p.pos || end+1 >= len(p.input) || p.input[end] != ':' || unicode.IsSpace(p.input[end+1]) {
+		return "", false
+	}
+	name := string(p.input[p.pos:end])
+	p.pos = end + 1
+	return name, true
+}
+
+// peekWord returns the word at the current position without consuming it.
+func (p *queryParser) peekWord() string {
+	end := p.pos
+	for end < len(p.input) && !isQueryDelimiter(p.input[end]) {
+		end++
+	}
+	return string(p.input[p.pos:end])
+}
+
+func (p *queryParser) skipSpace() {
+	for p.pos < len(p.input) && unicode.IsSpace(p.input[p.pos]) {
+		p.pos++
+	}
+}
+
+func isQueryDelimiter(r rune) bool {
+	return unicode.IsSpace(r) || r == '(' || r == ')' || r == '"'
+}
+
+// matches returns the docIDs matching a query node in order.
+func (idx *Index) matches(node queryNode) []int {
+	switch node := node.(type) {
+	case *termQuery:
+		return idx.termDocs(node.field, node.term)
+	case *phraseQuery:
+		var docs []int
+		for i, token := range node.tokens {
+			termDocs := idx.termDocs(node.field, token.Term)
+			if i == 0 {
+				docs = termDocs
+			} else {
+				docs = intersect(docs, termDocs)
+			}
+		}
+		matching := docs[:0:0]
+		for _, docID := range docs {
+			if idx.hasPhrase(docID, node.field, node.tokens) {
+				matching = append(matching, docID)
+			}
+		}
+		return matching
+	case *boolQuery:
+		var required, optional, excluded []int
+		hasRequired := false
+		for _, c := range node.clauses {
+			docs := idx.matches(c.node)
+			switch c.occur {
+			case must:
+				if hasRequired {
+					required = intersect(required, docs)
+				} else {
+					required = docs
+					hasRequired = true
+				}
+			case mustNot:
+				excluded = union(excluded, docs)
+			default:
+				optional = union(optional, docs)
+			}
+		}
+		if hasRequired {
+			return subtract(required, excluded)
+		}
+		return subtract(optional, excluded)
+	}
+	return nil
+}
+
+// termDocs returns the documents with the term in the field, or in any
+// field when field is empty.
+func (idx *Index) termDocs(field string, term string) []int {
+	postings, exists := idx.terms[term]
+	if !exists {
+		return nil
+	}
+	if field != "" {
+		fieldID, exists := idx.fieldIDs[field]
+		if !exists || fieldID >= len(postings.fields) || postings.fields[fieldID] == nil {
+			return nil
+		}
+		return postings.fields[fieldID].docIDs
+	}
+
+	var docs []int
+	for _, list := range postings.fields {
+		if list != nil {
+			docs = union(docs, list.docIDs)
+		}
+	}
+	return docs
+}
+
+// hasPhrase reports whether a field of the document, or the given field,
+// holds the tokens at the same relative positions as the query.
+func (idx *Index) hasPhrase(docID int, field string, tokens []Token) bool {
+	for fieldID, name := range idx.fieldNames {
+		if field != "" && name != field {
+			continue
+		}
+
+		positions := make([][]int32, len(tokens))
+		for i, token := range tokens {
+			postings := idx.terms[token.Term]
+			if postings == nil || fieldID >= len(postings.fields) || postings.fields[fieldID] == nil {
+				positions = nil
+				break
+			}
+			list := postings.fields[fieldID]
+			at := list.find(docID)
+			if at < 0 {
+				positions = nil
+				break
+			}
+			positions[i] = list.positionsAt(at)
+		}
+		if positions == nil {
+			continue
+		}
+
+	start:
+		for _, first := range positions[0] {
+			for i := 1; i < len(tokens); i++ {
+				want := first + int32(tokens[i].Position-tokens[0].Position)
+				if _, found := slices.BinarySearch(positions[i], want); !found {
+					continue start
+				}
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// scoringTerms returns the terms that add to the score of documents
+// matching the node, leaving out excluded clauses.
+func scoringTerms(node queryNode) []fieldTerm {
+	switch node := node.(type) {
+	case *termQuery:
+		return []fieldTerm{{field: node.field, term: node.term}}
+	case *phraseQuery:
+		terms := make([]fieldTerm, len(node.tokens))
+		for i, token := range node.tokens {
+			terms[i] = fieldTerm{field: node.field, term: token.Term}
+		}
+		return terms
+	case *boolQuery:
+		var terms []fieldTerm
+		for _, c := range node.clauses {
+			if c.occur != mustNot {
+				terms = append(terms, scoringTerms(c.node)...)
+			}
+		}
+		return terms
+	}
+	return nil
+}
+
+func intersect(a []int, b []int) []int {
+	var result []int
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] < b[j]:
+			i++
+		case a[i] > b[j]:
+			j++
+		default:
+			result = append(result, a[i])
+			i++
+			j++
+		}
+	}
+	return result
+}
+
+func union(a []int, b []int) []int {
+	if len(a) == 0 {
+		return b
+	}
+	if len(b) == 0 {
+		return a
+	}
+	result := make([]int, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] < b[j]:
+			result = append(result, a[i])
+			i++
+		case a[i] > b[j]:
+			result = append(result, b[j])
+			j++
+		default:
+			result = append(result, a[i])
+			i++
+			j++
+		}
+	}
+	result = append(result, a[i:]...)
+	return append(result, b[j:]...)
+}
+
+// subtract returns the docIDs of a that are not in b.
+func subtract(a []int, b []int) []int {
+	if len(b) == 0 {
+		return a
+	}
+	var result []int
+	j := 0
+	for _, docID := range a {
+		for j < len(b) && b[j] < docID {
+			j++
+		}
+		if j == len(b) || b[j] != docID {
+			result = append(result, docID)
+		}
+	}
+	return result
+}
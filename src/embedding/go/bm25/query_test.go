@@ -0,0 +1,198 @@
+package bm25
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSearchOperatorsAreNotTerms(t *testing.T) {
+	index := NewIndex()
+	for id, text := range map[string]string{
+		"1": "Jacy married Charlotte",
+		"2": "tea or coffee or juice or water",
+		"3": "Matt is a friend",
+	} {
+		if err := index.Add(id, text); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, query := range []string{"jacy OR matt", "(jacy OR matt)", "jacy || matt", "(jacy) matt"} {
+		var ids []string
+		for _, r := range index.Search(query, 10) {
+			ids = append(ids, r.ID)
+		}
+		slices.Sort(ids)
+		if !slices.Equal(ids, []string{"1", "3"}) {
+			t.Errorf("Search(%q) = %v, want 1 and 3", query, ids)
+		}
+		if score := index.Score(query, "2"); score != 0 {
+			t.Errorf("Score(%q, 2) = %v, want 0", query, score)
+		}
+	}
+}
+
+func TestPlainTermsShingles(t *testing.T) {
+	index := NewIndex()
+	index.Analyzer = NewAnalyzer(UnicodeTokenizer, Lowercase, Shingles(2))
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{query: "software engineer", want: []string{"software", "engineer", "software engineer"}},
+		{query: "software engineer OR lawyer", want: []string{"software", "engineer", "software engineer", "lawyer"}},
+		{query: "(jacy OR matt) charlotte", want: []string{"jacy", "matt", "charlotte"}},
+		{query: `"or" tea`, want: []string{"or", "tea"}},
+	}
+	for _, test := range tests {
+		var got []string
+		for _, term := range index.plainTerms(test.query) {
+			got = append(got, term.term)
+		}
+		if !slices.Equal(got, test.want) {
+			t.Errorf("plainTerms(%q) = %q, want %q", test.query, got, test.want)
+		}
+	}
+}
+
+// formatQuery writes a parsed query back in the query syntax, with every
+// group in parentheses.
+func formatQuery(node queryNode) string {
+	switch node := node.(type) {
+	case *termQuery:
+		if node.field != "" {
+			return node.field + ":" + node.term
+		}
+		return node.term
+	case *phraseQuery:
+		terms := make([]string, len(node.tokens))
+		for i, token := range node.tokens {
+			terms[i] = token.Term
+		}
+		phrase := `"` + strings.Join(terms, " ") + `"`
+		if node.field != "" {
+			return node.field + ":" + phrase
+		}
+		return phrase
+	case *boolQuery:
+		clauses := make([]string, len(node.clauses))
+		for i, c := range node.clauses {
+			clauses[i] = [...]string{should: "", must: "+", mustNot: "-"}[c.occur] + formatQuery(c.node)
+		}
+		return "(" + strings.Join(clauses, " ") + ")"
+	}
+	return fmt.Sprintf("%#v", node)
+}
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{query: "software engineer", want: "(software engineer)"},
+		{query: "Software ENGINEER", want: "(software engineer)"},
+		{query: `"software engineer"`, want: `("software engineer")`},
+		{query: `"Wilbur"`, want: "(wilbur)"},
+		{query: "+charlotte -lawyer", want: "(+charlotte -lawyer)"},
+		{query: "NOT lawyer jacy", want: "(-lawyer jacy)"},
+		{query: "jacy OR matt", want: "(jacy matt)"},
+		{query: "jacy AND charlotte", want: "(+jacy +charlotte)"},
+		{query: "a b AND c d", want: "(a +b +c d)"},
+		{query: "jacy || matt && married", want: "(jacy +matt +married)"},
+		{query: "-jacy AND matt", want: "(-jacy +matt)"},
+		{query: "(jacy OR matt) +married", want: "((jacy matt) +married)"},
+		{query: "(a AND (b OR -c)) d", want: "((+a +(b -c)) d)"},
+		{query: "title:python", want: "(title:python)"},
+		{query: `title:"machine learning"`, want: `(title:"machine learning")`},
+		{query: "title:(python OR go)", want: "((title:python title:go))"},
+		{query: "title:(python content:go)", want: "((title:python content:go))"},
+		{query: "-title:draft report", want: "(-title:draft report)"},
+		{query: "e-mail", want: "((e mail))"},
+		{query: `"e-mail"`, want: `("e mail")`},
+
+		// Malformed queries are parsed leniently
+		{query: "", want: "()"},
+		{query: `"software engineer`, want: `("software engineer")`},
+		{query: "(jacy OR matt", want: "((jacy matt))"},
+		{query: "((jacy)", want: "(((jacy)))"},
+		{query: "jacy) matt", want: "(jacy matt)"},
+		{query: "()", want: "()"},
+		{query: `""`, want: "()"},
+		{query: "+ -", want: "()"},
+		{query: "AND OR NOT", want: "()"},
+		{query: "jacy AND", want: "(jacy)"},
+		{query: "title:", want: "(title)"},
+		{query: "title: python", want: "(title python)"},
+		{query: ":python", want: "(python)"},
+	}
+	index := NewIndex()
+	for _, test := range tests {
+		if got := formatQuery(index.parseQuery(test.query)); got != test.want {
+			t.Errorf("parseQuery(%q) = %s, want %s", test.query, got, test.want)
+		}
+	}
+}
+
+func TestSearchQuery(t *testing.T) {
+	index := NewIndex()
+	for id, fields := range map[string]map[string]string{
+		"1": {"title": "Jacy", "content": "Jacy is a software engineer"},
+		"2": {"title": "Charlotte", "content": "Charlotte is a lawyer and an engineer"},
+		"3": {"title": "Matt", "content": "Matt is a software developer married to an engineer"},
+		"4": {"content": "engineer software"},
+		"5": {"title": "Machine learning with Python", "content": "A book about learning machines"},
+		"6": {"title": "Go in action", "content": "Not a python book"},
+	} {
+		if err := index.AddFields(id, fields); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{query: "software engineer", want: []string{"1", "2", "3", "4"}},
+		{query: `"software engineer"`, want: []string{"1"}},
+		{query: `"engineer software"`, want: []string{"4"}},
+		{query: "+software -developer", want: []string{"1", "4"}},
+		{query: "engineer NOT lawyer", want: []string{"1", "3", "4"}},
+		{query: "software AND married", want: []string{"3"}},
+		{query: "(jacy OR matt) AND engineer", want: []string{"1", "3"}},
+		{query: "(jacy OR charlotte) -(lawyer OR developer)", want: []string{"1"}},
+		{query: "python", want: []string{"5", "6"}},
+		{query: "title:python", want: []string{"5"}},
+		{query: `title:"machine learning"`, want: []string{"5"}},
+		{query: `"machine learning"`, want: []string{"5"}},
+		{query: `content:"machine learning"`, want: nil},
+		{query: "title:(python OR go)", want: []string{"5", "6"}},
+		{query: "+book -title:go", want: []string{"5"}},
+		{query: "-engineer", want: nil},
+		{query: "+nowhere engineer", want: nil},
+		{query: `"software engineer`, want: []string{"1"}},
+		{query: "(jacy OR matt", want: []string{"1", "3"}},
+	}
+	for _, test := range tests {
+		results := index.Search(test.query, 10)
+		var ids []string
+		for _, r := range results {
+			ids = append(ids, r.ID)
+			if score := index.Score(test.query, r.ID); math.Abs(score-r.Score) > 1e-9 {
+				t.Errorf("Score(%q, %s) = %v, want %v as searched", test.query, r.ID, score, r.Score)
+			}
+		}
+		slices.Sort(ids)
+		if !slices.Equal(ids, test.want) {
+			t.Errorf("Search(%q) = %v, want %v", test.query, ids, test.want)
+		}
+		for _, id := range []string{"1", "2", "3", "4", "5", "6"} {
+			if !slices.Contains(ids, id) && index.Score(test.query, id) != 0 {
+				t.Errorf("Score(%q, %s) = %v, want 0 for a document not found", test.query, id, index.Score(test.query, id))
+			}
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package bm25
+
+import (
+	"fmt"
+	"math"
+)
+
+// Scoring selects the BM25 variant. All of them score documents with
+// several fields as BM25F: the term frequencies of the fields are length
+// normalised, weighted and summed before saturation.
+type Scoring int
+
+const (
+	// Okapi is classic BM25.
+	Okapi Scoring = iota
+	// BM25Plus adds Delta to every matching term, so a long document
+	// containing a term never scores below a short one without it
+	// (Lv and Zhai, "Lower-Bounding Term Frequency Normalization", 2011).
+	BM25Plus
+	// BM25L shifts the normalised term frequency by Delta, penalising long
+	// documents less (Lv and Zhai, "When Documents Are Very Long, BM25 Fails!", 2011).
+	BM25L
+)
+
+var scoringNames = map[Scoring]string{
+	Okapi:    "okapi",
+	BM25Plus: "bm25+",
+	BM25L:    "bm25l",
+}
+
+func (s Scoring) String() string {
+	if name, ok := scoringNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("Scoring(%d)", int(s))
+}
+
+// ParseScoring returns the variant named okapi, bm25+ or bm25l.
+func ParseScoring(name string) (Scoring, error) {
+	for scoring, scoringName := range scoringNames {
+		if name == scoringName {
+			return scoring, nil
+		}
+	}
+	return 0, fmt.Errorf("bm25: unknown scoring %q", name)
+}
+
+// scorer holds the scoring settings and collection statistics of a search.
+// It is made while the index is read locked.
+type scorer struct {
+	k1             float64
+	scoring        Scoring
+	delta          float64
+	nonNegativeIDF bool
+	n              int
+	weights        []float64 // fieldID -> field weight
+	bs             []float64 // fieldID -> field b
+	avgLengths     []float64 // fieldID -> average field length
+	lengths        [][]int   // fieldID -> docID -> field length
+}
+
+func (idx *Index) scorer() *scorer {
+	s := &scorer{
+		k1:             idx.K1,
+		scoring:        idx.Scoring,
+		delta:          idx.Delta,
+		nonNegativeIDF: idx.NonNegativeIDF,
+		n:              len(idx.ids),
+		lengths:        idx.lengths,
+	}
+	if s.delta == 0 {
+		switch s.scoring {
+		case BM25Plus:
+			s.delta = 1
+		case BM25L:
+			s.delta = 0.5
+		}
+	}
+
+	for fieldID, name := range idx.fieldNames {
+		field, ok := idx.Fields[name]
+		if !ok {
+			field = Field{Weight: 1, B: idx.B}
+		}
+		s.weights = append(s.weights, field.Weight)
+		s.bs = append(s.bs, field.B)
+		avgLength := 0.0
+		if s.n > 0 {
+			avgLength = float64(idx.totalLengths[fieldID]) / float64(s.n)
+		}
+		s.avgLengths = append(s.avgLengths, avgLength)
+	}
+	return s
+}
+
+// idf computes the inverse document frequency of a term in df documents.
+func (s *scorer) idf(df int) float64 {
+	ratio := (float64(s.n) - float64(df) + 0.5) / (float64(df) + 0.5)
+	if s.nonNegativeIDF {
+		return math.Log(1 + ratio)
+	}
+	return math.Log(ratio)
+}
+
+// fieldFreq is the weighted, length normalised frequency of a term in a
+// field of a document. BM25F sums it over the fields.
+func (s *scorer) fieldFreq(fieldID int, termFreq int, docID int) float64 {
+	if termFreq == 0 || s.avgLengths[fieldID] == 0 {
+		return 0
+	}
+	b := s.bs[fieldID]
+	length := float64(s.lengths[fieldID][docID])
+	return s.weights[fieldID] * float64(termFreq) / (1 - b + b*length/s.avgLengths[fieldID])
+}
+
+// maxFieldFreq bounds fieldFreq for a term occurring at most maxTermFreq
+// times in the field, reached in the shortest possible field.
+func (s *scorer) maxFieldFreq(fieldID int, maxTermFreq int) float64 {
+	b := s.bs[fieldID]
+	if b < 0 || b >= 1 {
+		// The frequency no longer falls with length or has no bound
+		return math.Inf(1)
+	}
+	return s.weights[fieldID] * float64(maxTermFreq) / (1 - b)
+}
+
+// saturate turns the summed field frequencies of a matching term into the
+// factor its IDF is multiplied by.
+func (s *scorer) saturate(freq float64) float64 {
+	if freq <= 0 {
+		return 0
+	}
+	k1 := s.k1
+	switch s.scoring {
+	case BM25Plus:
+		if math.IsInf(freq, 1) {
+			return k1 + 1 + s.delta
+		}
+		return freq*(k1+1)/(freq+k1) + s.delta
+	case BM25L:
+		if math.IsInf(freq, 1) {
+			return k1 + 1
+		}
+		return (k1 + 1) * (freq + s.delta) / (k1 + freq + s.delta)
+	default:
+		if math.IsInf(freq, 1) {
+			return k1 + 1
+		}
+		return freq * (k1 + 1) / (freq + k1)
+	}
+}
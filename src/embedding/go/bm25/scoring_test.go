@@ -0,0 +1,93 @@
+package bm25
+
+import (
+	"math"
+	"testing"
+)
+
+// TestScoringGolden checks every scoring variant against scores computed by
+// hand.
+func TestScoringGolden(t *testing.T) {
+	for _, c := range goldenCases {
+		t.Run(c.name, func(t *testing.T) {
+			got := c.index().Score(c.query, c.id)
+			if math.Abs(got-c.want) > 1e-9 {
+				t.Errorf("got %.12f, want %.12f", got, c.want)
+			}
+		})
+	}
+}
+
+type goldenCase struct {
+	name  string
+	index func() *Index
+	query string
+	id    string
+	want  float64
+}
+
+// With k1 = 1.5 and b = 0.75, the corpus of goldenCorpus has N = 3 and an average
+// length of 10/3:
+//
+//	d1 "the cat sat"          length 3, norm = 1 - b + b*3/(10/3) = 0.925
+//	d2 "the cat and the dog"  length 5, norm = 1 - b + b*5/(10/3) = 1.375
+//	d3 "a bird"               length 2
+//
+// "cat" is in 2 documents: IDF = ln((3-2+0.5)/(2+0.5)) = ln(0.6), or
+// ln(1+0.6) when non-negative. "dog" is in 1: IDF = ln(2.5/1.5).
+// A single occurrence saturates to tf(k1+1)/(tf+k1*norm), which is
+// 2.5/2.3875 in d1 and 2.5/3.0625 in d2.
+func goldenCorpus(scoring Scoring, nonNegativeIDF bool) func() *Index {
+	return func() *Index {
+		index := NewIndex()
+		index.Scoring = scoring
+		index.NonNegativeIDF = nonNegativeIDF
+		index.Add("d1", "the cat sat")
+		index.Add("d2", "the cat and the dog")
+		index.Add("d3", "a bird")
+		return index
+	}
+}
+
+// goldenFieldCorpus has a title with weight 2 and a content field, N = 2:
+//
+//	d1 title "cat" (length 1), content "the dog sat" (length 3)
+//	d2 title "dog" (length 1), content "the cat" (length 2)
+//
+// Average lengths are 1 for titles and 2.5 for content.
+func goldenFieldCorpus() *Index {
+	index := NewIndex()
+	index.NonNegativeIDF = true
+	index.Fields = map[string]Field{
+		"title":   {Weight: 2, B: 0.75},
+		"content": {Weight: 1, B: 0.75},
+	}
+	index.AddFields("d1", map[string]string{"title": "cat", "content": "the dog sat"})
+	index.AddFields("d2", map[string]string{"title": "dog", "content": "the cat"})
+	return index
+}
+
+var goldenCases = []goldenCase{
+	// ln(0.6) * 2.5/2.3875: a term in most documents lowers the score
+	{"okapi common term", goldenCorpus(Okapi, false), "cat", "d1", -0.5348959411162206},
+	// ln(0.6) * 2.5/3.0625: the longer document is penalised less by the
+	// negative IDF
+	{"okapi length normalization", goldenCorpus(Okapi, false), "cat", "d2", -0.41700050919672715},
+	// ln(1.6) * 2.5/2.3875
+	{"okapi non-negative idf", goldenCorpus(Okapi, true), "cat", "d1", 0.49215039711595354},
+	// ln(2.5/1.5) * 2.5/3.0625
+	{"okapi rare term", goldenCorpus(Okapi, false), "dog", "d2", 0.41700050919672715},
+	// Repeating a query term counts it twice: 2 * ln(2.5/1.5) * 2.5/3.0625
+	{"okapi repeated query term", goldenCorpus(Okapi, false), "dog dog", "d2", 0.8340010183934543},
+	{"okapi no match", goldenCorpus(Okapi, false), "dog", "d1", 0},
+	// ln(2.5/1.5) * (2.5/3.0625 + 1), delta 1
+	{"bm25+", goldenCorpus(BM25Plus, false), "dog", "d2", 0.9278261329627179},
+	// c = 1/1.375, ln(2.5/1.5) * 2.5(c+0.5)/(1.5+c+0.5), delta 0.5
+	{"bm25l", goldenCorpus(BM25L, false), "dog", "d2", 0.5746788267367394},
+	// IDF = ln(1 + 0.5/2.5). In the title: freq = 2*1/1 = 2, saturated
+	// 2*2.5/(2+1.5)
+	{"bm25f title match", goldenFieldCorpus, "cat", "d1", 0.26045936684850657},
+	// In the content: freq = 1/(0.25 + 0.75*2/2.5) = 1/0.85, saturated
+	// freq*2.5/(freq+1.5)
+	{"bm25f content match", goldenFieldCorpus, "cat", "d2", 0.20035335911423582},
+}
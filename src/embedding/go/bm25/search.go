@@ -0,0 +1,359 @@
+package bm25
+
+import (
+	"container/heap"
+	"math"
+	"slices"
+	"sort"
+)
+
+// Search returns the k highest scoring documents matching the query, best
+// first. Ties are broken by ID. See query.go for the query syntax; a query
+// without it matches documents containing at least one of its terms.
+//
+// Only the postings of the query terms are read, walking them together in
+// docID order and keeping the best k documents in a heap. With MaxScore set,
+// terms whose combined upper bounds cannot beat the k-th best score so far
+// are only used to finish scoring documents found through the other terms,
+// and a document is dropped as soon as its remaining terms cannot lift it
+// into the top k. Both ways return the same results.
+func (idx *Index) Search(query string, k int) []Result {
+	q := idx.parseQuery(query)
+	var terms []fieldTerm
+	if q.plain() {
+		terms = idx.plainTerms(query)
+	}
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	if k <= 0 || len(idx.ids) == 0 {
+		return nil
+	}
+
+	s := idx.scorer()
+	var hits []hit
+	if q.plain() {
+		hits = idx.searchTerms(s, terms, k)
+	} else {
+		hits = idx.searchQuery(s, q, k)
+	}
+	results := make([]Result, len(hits))
+	for i, h := range hits {
+		results[i] = idx.result(idx.docs[h.docID], h.score)
+	}
+	return results
+}
+
+// searchTerms ranks the documents containing any of the terms.
+func (idx *Index) searchTerms(s *scorer, terms []fieldTerm, k int) []hit {
+	cursors := idx.cursors(s, terms)
+	sort.Slice(cursors, func(a int, b int) bool {
+		return cursors[a].upperBound < cursors[b].upperBound
+	})
+	// bounds[i] is the most the terms of cursors[0..i] can add to a score.
+	bounds := make([]float64, len(cursors))
+	sum := 0.0
+	for i, c := range cursors {
+		sum += c.upperBound
+		bounds[i] = sum
+	}
+
+	top := &topK{k: k}
+	contributions := make([]float64, len(cursors))
+	// Cursors before firstEssential are non-essential: documents only
+	// containing their terms score below the threshold.
+	firstEssential := 0
+
+	for {
+		if idx.MaxScore {
+			for firstEssential < len(cursors) && bounds[firstEssential] < top.threshold() {
+				firstEssential++
+			}
+		}
+
+		docID := math.MaxInt
+		for _, c := range cursors[firstEssential:] {
+			docID = min(docID, c.doc())
+		}
+		if docID == math.MaxInt {
+			break
+		}
+
+		clear(contributions)
+		score := 0.0
+		for i, c := range cursors[firstEssential:] {
+			if c.doc() == docID {
+				contributions[firstEssential+i] = c.score(s, docID)
+				score += contributions[firstEssential+i]
+				c.next(docID)
+			}
+		}
+
+		competitive := true
+		for i := firstEssential - 1; i >= 0; i-- {
+			if score+bounds[i] < top.threshold() {
+				competitive = false
+				break
+			}
+			c := cursors[i]
+			c.seek(docID)
+			if c.doc() == docID {
+				contributions[i] = c.score(s, docID)
+				score += contributions[i]
+			}
+		}
+
+		if !competitive {
+			continue
+		}
+		// Summed in cursor order, so the score does not depend on which
+		// terms were essential and ties break the same way.
+		score = 0
+		for _, contribution := range contributions {
+			score += contribution
+		}
+		// The document is only looked up when it can make the top k
+		if score < top.threshold() {
+			continue
+		}
+		top.offer(hit{docID: docID, id: idx.docs[docID].id, score: score})
+	}
+	return top.sorted()
+}
+
+// searchQuery ranks the documents matching a query with operators, scoring
+// them by the terms outside excluded clauses.
+func (idx *Index) searchQuery(s *scorer, q *boolQuery, k int) []hit {
+	cursors := idx.cursors(s, scoringTerms(q))
+	top := &topK{k: k}
+	for _, docID := range idx.matches(q) {
+		score := 0.0
+		for _, c := range cursors {
+			c.seek(docID)
+			if c.doc() == docID {
+				score += c.score(s, docID)
+			}
+		}
+		if score < top.threshold() {
+			continue
+		}
+		top.offer(hit{docID: docID, id: idx.docs[docID].id, score: score})
+	}
+	return top.sorted()
+}
+
+// Score computes the BM25 score of the document with id for the query, zero
+// if it does not match.
+func (idx *Index) Score(query string, id string) float64 {
+	q := idx.parseQuery(query)
+	var terms []fieldTerm
+	if q.plain() {
+		terms = idx.plainTerms(query)
+	}
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	docID, exists := idx.ids[id]
+	if !exists {
+		return 0
+	}
+	if !q.plain() {
+		if _, found := slices.BinarySearch(idx.matches(q), docID); !found {
+			return 0
+		}
+		terms = scoringTerms(q)
+	}
+
+	s := idx.scorer()
+	score := 0.0
+	for _, c := range idx.cursors(s, terms) {
+		c.seek(docID)
+		if c.doc() == docID {
+			score += c.score(s, docID)
+		}
+	}
+	return score
+}
+
+// cursor walks the postings of a query term in every field together.
+type cursor struct {
+	fields []fieldCursor
+	// current is the docID the cursor is on, see doc.
+	current int
+	// weight is how often the term occurs in the query.
+	weight     float64
+	idf        float64
+	upperBound float64
+}
+
+type fieldCursor struct {
+	fieldID int
+	list    *postingList
+	pos     int
+}
+
+// cursors returns a cursor per distinct query term found in the index. A
+// term restricted to a field only reads the postings of that field.
+func (idx *Index) cursors(s *scorer, terms []fieldTerm) []*cursor {
+	byTerm := make(map[fieldTerm]*cursor)
+	var cursors []*cursor
+	for _, term := range terms {
+		if c, exists := byTerm[term]; exists {
+			c.weight++
+			continue
+		}
+		postings, exists := idx.terms[term.term]
+		if !exists {
+			continue
+		}
+		c := &cursor{weight: 1, idf: s.idf(postings.docFreq)}
+		for fieldID, list := range postings.fields {
+			if term.field != "" && idx.fieldNames[fieldID] != term.field {
+				continue
+			}
+			if list != nil && len(list.docIDs) > 0 {
+				c.fields = append(c.fields, fieldCursor{fieldID: fieldID, list: list})
+			}
+		}
+		if len(c.fields) == 0 {
+			continue
+		}
+		c.update()
+		byTerm[term] = c
+		cursors = append(cursors, c)
+	}
+
+	for _, c := range cursors {
+		c.upperBound = c.bound(s)
+	}
+	return cursors
+}
+
+// bound is the most the term of c can add to a document's score: its
+// highest frequency in every field of the shortest possible document.
+func (c *cursor) bound(s *scorer) float64 {
+	if c.idf <= 0 {
+		return 0
+	}
+	freq := 0.0
+	for _, f := range c.fields {
+		freq += s.maxFieldFreq(f.fieldID, f.list.maxTermFreq)
+	}
+	return c.weight * c.idf * s.saturate(freq)
+}
+
+// doc returns the current docID, or math.MaxInt once the postings are done.
+func (c *cursor) doc() int {
+	return c.current
+}
+
+// update sets the current docID after the field cursors moved.
+func (c *cursor) update() {
+	c.current = math.MaxInt
+	for _, f := range c.fields {
+		if f.pos < len(f.list.docIDs) {
+			c.current = min(c.current, f.list.docIDs[f.pos])
+		}
+	}
+}
+
+// next moves past docID, which must be the current document.
+func (c *cursor) next(docID int) {
+	for i := range c.fields {
+		f := &c.fields[i]
+		if f.pos < len(f.list.docIDs) && f.list.docIDs[f.pos] == docID {
+			f.pos++
+		}
+	}
+	c.update()
+}
+
+// seek moves to the first document at or after docID.
+func (c *cursor) seek(docID int) {
+	if c.current >= docID {
+		return
+	}
+	for i := range c.fields {
+		f := &c.fields[i]
+		f.pos += sort.SearchInts(f.list.docIDs[f.pos:], docID)
+	}
+	c.update()
+}
+
+// score is the term's contribution to docID, the current document.
+func (c *cursor) score(s *scorer, docID int) float64 {
+	freq := 0.0
+	for _, f := range c.fields {
+		if f.pos < len(f.list.docIDs) && f.list.docIDs[f.pos] == docID {
+			freq += s.fieldFreq(f.fieldID, f.list.termFreqs[f.pos], docID)
+		}
+	}
+	return c.weight * c.idf * s.saturate(freq)
+}
+
+// anyField returns the terms unrestricted to a field.
+func anyField(terms []string) []fieldTerm {
+	fieldTerms := make([]fieldTerm, len(terms))
+	for i, term := range terms {
+		fieldTerms[i] = fieldTerm{term: term}
+	}
+	return fieldTerms
+}
+
+// hit is a scored document kept by topK.
+type hit struct {
+	docID int
+	id    string
+	score float64
+}
+
+// topK keeps the k best hits seen, worst first.
+type topK struct {
+	k    int
+	hits []hit
+}
+
+func (t *topK) Len() int           { return len(t.hits) }
+func (t *topK) Less(a, b int) bool { return worse(t.hits[a], t.hits[b]) }
+func (t *topK) Swap(a, b int)      { t.hits[a], t.hits[b] = t.hits[b], t.hits[a] }
+func (t *topK) Push(x any)         { t.hits = append(t.hits, x.(hit)) }
+func (t *topK) Pop() any {
+	last := t.hits[len(t.hits)-1]
+	t.hits = t.hits[:len(t.hits)-1]
+	return last
+}
+
+// threshold is the score a hit has to reach to be kept.
+func (t *topK) threshold() float64 {
+	if len(t.hits) < t.k {
+		return math.Inf(-1)
+	}
+	return t.hits[0].score
+}
+
+func (t *topK) offer(h hit) {
+	if len(t.hits) < t.k {
+		heap.Push(t, h)
+	} else if worse(t.hits[0], h) {
+		t.hits[0] = h
+		heap.Fix(t, 0)
+	}
+}
+
+// sorted returns the hits best first.
+func (t *topK) sorted() []hit {
+	hits := make([]hit, len(t.hits))
+	for i := len(hits) - 1; i >= 0; i-- {
+		hits[i] = heap.Pop(t).(hit)
+	}
+	return hits
+}
+
+func worse(a hit, b hit) bool {
+	if a.score != b.score {
+		return a.score < b.score
+	}
+	return a.id > b.id
+}
@@ -0,0 +1,116 @@
+package bm25
+
+import (
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// zipfCorpus builds an index of synthetic documents whose term frequencies
+// follow Zipf's law, like natural language, and queries mixing common and
+// rare terms.
+func zipfCorpus(tb testing.TB, docs int, queries int, seed int64) (*Index, []string) {
+	tb.Helper()
+	const (
+		docLength   = 50
+		vocabulary  = 100_000
+		queryLength = 4
+	)
+
+	rng := rand.New(rand.NewSource(seed))
+	zipf := rand.NewZipf(rng, 1.1, 1, vocabulary-1)
+	words := func(n int) string {
+		var sb strings.Builder
+		for i := 0; i < n; i++ {
+			sb.WriteString(" w")
+			sb.WriteString(strconv.FormatUint(zipf.Uint64(), 36))
+		}
+		return sb.String()
+	}
+
+	index := NewIndex()
+	for i := 0; i < docs; i++ {
+		if err := index.Add(strconv.Itoa(i), words(docLength/2+rng.Intn(docLength+1))); err != nil {
+			tb.Fatal(err)
+		}
+	}
+	querySet := make([]string, queries)
+	for i := range querySet {
+		querySet[i] = words(queryLength)
+	}
+	return index, querySet
+}
+
+func TestMaxScoreMatchesExhaustive(t *testing.T) {
+	docs := 20_000
+	if testing.Short() {
+		docs = 2_000
+	}
+	index, queries := zipfCorpus(t, docs, 100, 1)
+
+	for _, scoring := range []Scoring{Okapi, BM25Plus, BM25L} {
+		index.Scoring = scoring
+		for _, k := range []int{1, 10, 100} {
+			for _, query := range queries {
+				index.MaxScore = false
+				want := index.Search(query, k)
+				index.MaxScore = true
+				got := index.Search(query, k)
+
+				if !sameResults(got, want) {
+					t.Fatalf("%s k=%d: MaxScore results for %q differ:\ngot  %v\nwant %v", scoring, k, query, summary(got), summary(want))
+				}
+			}
+		}
+	}
+}
+
+func sameResults(a []Result, b []Result) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].ID != b[i].ID || math.Abs(a[i].Score-b[i].Score) > 1e-9 {
+			return false
+		}
+	}
+	return true
+}
+
+func summary(results []Result) []string {
+	s := make([]string, len(results))
+	for i, r := range results {
+		s[i] = r.ID + ":" + strconv.FormatFloat(r.Score, 'f', 4, 64)
+	}
+	return s
+}
+
+var (
+	benchOnce    sync.Once
+	benchIndex   *Index
+	benchQueries []string
+)
+
+// BenchmarkSearch searches a corpus of a million documents, which takes a
+// few GB of memory and a while to build.
+func BenchmarkSearch(b *testing.B) {
+	if testing.Short() {
+		b.Skip("skipping the 1M document corpus in short mode")
+	}
+	benchOnce.Do(func() {
+		benchIndex, benchQueries = zipfCorpus(b, 1_000_000, 200, 1)
+	})
+
+	for _, maxScore := range []bool{false, true} {
+		b.Run("maxscore="+strconv.FormatBool(maxScore), func(b *testing.B) {
+			benchIndex.MaxScore = maxScore
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				benchIndex.Search(benchQueries[i%len(benchQueries)], 10)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package bm25
+
+import (
+	"bufio"
+	"embed"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+//go:embed stopwords/*.txt
+var builtinStopwords embed.FS
+
+// EnglishStopwords returns the built-in list of common English words.
+func EnglishStopwords() map[string]bool {
+	f, err := builtinStopwords.Open("stopwords/english.txt")
+	if err != nil {
+		panic(err)
+	}
+	defer f.Close()
+
+	words, err := readStopwords(f)
+	if err != nil {
+		panic(err)
+	}
+	return words
+}
+
+// LoadStopwords reads stopword files with one word per line. Blank lines and
+// lines starting with # are ignored, and words are lower cased.
+func LoadStopwords(paths ...string) (map[string]bool, error) {
+	words := make(map[string]bool)
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("error loading stopwords: %w", err)
+		}
+		fileWords, err := readStopwords(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("error loading stopwords from %s: %w", path, err)
+		}
+		for word := range fileWords {
+			words[word] = true
+		}
+	}
+	return words, nil
+}
+
+func readStopwords(r io.Reader) (map[string]bool, error) {
+	words := make(map[string]bool)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		words[strings.ToLower(line)] = true
+	}
+	return words, scanner.Err()
+}
+
+// SortedWords returns the words of a stopword set in order.
+func SortedWords(words map[string]bool) []string {
+	sorted := make([]string, 0, len(words))
+	for word, ok := range words {
+		if ok {
+			sorted = append(sorted, word)
+		}
+	}
+	sort.Strings(sorted)
+	return sorted
+}
@@ -0,0 +1,208 @@
+// Package indexfile frames the binary files indexes are saved to.
+//
+// A file starts with an 8 byte magic number naming the index type and a
+// format version, followed by the body and a CRC-32C checksum of everything
+// before it. Integers in the body are unsigned varints, byte strings are
+// length prefixed.
+//
+//	magic     8 bytes
+//	version   uint32, little endian
+//	body      written with an Encoder, read with a Decoder
+//	checksum  uint32, little endian
+package indexfile
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+)
+
+var (
+	ErrNotIndexFile = errors.New("not an index file")
+	ErrChecksum     = errors.New("checksum mismatch")
+)
+
+// VersionError is returned by NewDecoder for a file of another format version.
+type VersionError struct {
+	Version uint32
+}
+
+func (e *VersionError) Error() string {
+	return fmt.Sprintf("unsupported version %d", e.Version)
+}
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// Save writes src to path. The file is written next to it first and renamed,
+// so readers never see a partial index. Errors of src are returned as is.
+func Save(path string, src io.WriterTo) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("error saving index: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(0o644); err != nil {
+		tmp.Close()
+		return fmt.Errorf("error saving index: %w", err)
+	}
+
+	w := bufio.NewWriter(tmp)
+	if _, err := src.WriteTo(w); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("error saving index: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("error saving index: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("error saving index: %w", err)
+	}
+	return nil
+}
+
+// Encoder buffers the body of a file.
+type Encoder struct {
+	buf bytes.Buffer
+}
+
+// NewEncoder returns an encoder of a file with the given magic and version.
+func NewEncoder(magic string, version uint32) *Encoder {
+	e := &Encoder{}
+	e.buf.WriteString(magic)
+	e.Uint32(version)
+	return e
+}
+
+func (e *Encoder) Uvarint(v int) {
+	e.buf.Write(binary.AppendUvarint(nil, uint64(v)))
+}
+
+func (e *Encoder) Bytes(b []byte) {
+	e.Uvarint(len(b))
+	e.buf.Write(b)
+}
+
+func (e *Encoder) Uint32(v uint32) {
+	e.buf.Write(binary.LittleEndian.AppendUint32(nil, v))
+}
+
+// Float32s writes the bits of each component, the length is not written.
+func (e *Encoder) Float32s(v []float32) {
+	for _, f := range v {
+		e.Uint32(math.Float32bits(f))
+	}
+}
+
+// WriteTo writes the file followed by its checksum.
+func (e *Encoder) WriteTo(w io.Writer) (int64, error) {
+	sum := binary.LittleEndian.AppendUint32(nil, crc32.Checksum(e.buf.Bytes(), castagnoli))
+	n, err := w.Write(append(e.buf.Bytes(), sum...))
+	if err != nil {
+		return int64(n), fmt.Errorf("error writing index: %w", err)
+	}
+	return int64(n), nil
+}
+
+// Decoder reads the body of a file. After the first error every read returns
+// a zero value and the error is kept.
+type Decoder struct {
+	data []byte
+	err  error
+}
+
+// NewDecoder checks the magic, version and checksum of data and returns a
+// decoder of its body.
+func NewDecoder(data []byte, magic string, version uint32) (*Decoder, error) {
+	if len(data) < len(magic)+4+4 || string(data[:len(magic)]) != magic {
+		return nil, ErrNotIndexFile
+	}
+	if v := binary.LittleEndian.Uint32(data[len(magic):]); v != version {
+		return nil, &VersionError{Version: v}
+	}
+	body, sum := data[:len(data)-4], binary.LittleEndian.Uint32(data[len(data)-4:])
+	if crc32.Checksum(body, castagnoli) != sum {
+		return nil, ErrChecksum
+	}
+	return &Decoder{data: body[len(magic)+4:]}, nil
+}
+
+// Err returns the first error of the decoder.
+func (d *Decoder) Err() error {
+	return d.err
+}
+
+// Fail records err unless the decoder already failed.
+func (d *Decoder) Fail(err error) {
+	if d.err == nil {
+		d.err = err
+	}
+}
+
+// Len returns the number of unread bytes, an upper bound for the number of
+// elements still to come.
+func (d *Decoder) Len() int {
+	return len(d.data)
+}
+
+// Finish returns the first error of the decoder, or an error if the body has
+// unread data.
+func (d *Decoder) Finish() error {
+	if d.err == nil && len(d.data) > 0 {
+		d.err = errors.New("trailing data")
+	}
+	return d.err
+}
+
+func (d *Decoder) take(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if n < 0 || n > len(d.data) {
+		d.err = io.ErrUnexpectedEOF
+		return nil
+	}
+	b := d.data[:n]
+	d.data = d.data[n:]
+	return b
+}
+
+func (d *Decoder) Uvarint() int {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(d.data)
+	if n <= 0 || v > math.MaxInt32 {
+		d.err = errors.New("invalid varint")
+		return 0
+	}
+	d.data = d.data[n:]
+	return int(v)
+}
+
+func (d *Decoder) Bytes() []byte {
+	return d.take(d.Uvarint())
+}
+
+// Float32s reads n components written with Encoder.Float32s.
+func (d *Decoder) Float32s(n int) []float32 {
+	raw := d.take(4 * n)
+	if d.err != nil {
+		return nil
+	}
+	v := make([]float32, n)
+	for i := range v {
+		v[i] = math.Float32frombits(binary.LittleEndian.Uint32(raw[4*i:]))
+	}
+	return v
+}
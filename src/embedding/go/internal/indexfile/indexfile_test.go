@@ -0,0 +1,124 @@
+package indexfile
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const testMagic = "TESTIDX\x00"
+
+func encode(t *testing.T, e *Encoder) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if _, err := e.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestRoundTrip(t *testing.T) {
+	e := NewEncoder(testMagic, 2)
+	e.Uvarint(300)
+	e.Bytes([]byte("term"))
+	e.Float32s([]float32{1.5, -2, 0})
+	data := encode(t, e)
+
+	d, err := NewDecoder(data, testMagic, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := d.Uvarint(); got != 300 {
+		t.Errorf("got %d, want 300", got)
+	}
+	if got := string(d.Bytes()); got != "term" {
+		t.Errorf("got %q, want %q", got, "term")
+	}
+	if got, want := d.Float32s(3), []float32{1.5, -2, 0}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if err := d.Finish(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestNewDecoderErrors(t *testing.T) {
+	e := NewEncoder(testMagic, 2)
+	e.Bytes([]byte("body"))
+	data := encode(t, e)
+	flipped := bytes.Clone(data)
+	flipped[len(testMagic)+5] ^= 0xff
+
+	if _, err := NewDecoder(data[:10], testMagic, 2); !errors.Is(err, ErrNotIndexFile) {
+		t.Errorf("got error %v for a short file, want %v", err, ErrNotIndexFile)
+	}
+	if _, err := NewDecoder(data, "OTHERIDX", 2); !errors.Is(err, ErrNotIndexFile) {
+		t.Errorf("got error %v for another magic, want %v", err, ErrNotIndexFile)
+	}
+	var versionErr *VersionError
+	if _, err := NewDecoder(data, testMagic, 3); !errors.As(err, &versionErr) || versionErr.Version != 2 {
+		t.Errorf("got error %v for another version, want version 2", err)
+	}
+	if _, err := NewDecoder(flipped, testMagic, 2); !errors.Is(err, ErrChecksum) {
+		t.Errorf("got error %v for a flipped byte, want %v", err, ErrChecksum)
+	}
+}
+
+func TestDecoderKeepsFirstError(t *testing.T) {
+	e := NewEncoder(testMagic, 1)
+	e.Bytes([]byte("ab"))
+	e.Uvarint(7)
+	data := encode(t, e)
+
+	d, err := NewDecoder(data, testMagic, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := d.Float32s(10); got != nil || !errors.Is(d.Err(), io.ErrUnexpectedEOF) {
+		t.Errorf("got %v, %v reading past the end", got, d.Err())
+	}
+	d.Fail(errors.New("later"))
+	if got := d.Uvarint(); got != 0 || !errors.Is(d.Finish(), io.ErrUnexpectedEOF) {
+		t.Errorf("got %d, %v after the first error", got, d.Err())
+	}
+
+	d, _ = NewDecoder(data, testMagic, 1)
+	d.Bytes()
+	if err := d.Finish(); err == nil {
+		t.Error("got no error for trailing data")
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) WriteTo(io.Writer) (int64, error) {
+	return 0, errors.New("cannot encode")
+}
+
+func TestSave(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "index")
+	e := NewEncoder(testMagic, 1)
+	e.Bytes([]byte("first"))
+	if err := Save(path, e); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Save(path, failingWriter{}); err == nil || err.Error() != "cannot encode" {
+		t.Errorf("got error %v, want the error of the writer", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, encode(t, e)) {
+		t.Error("failed save changed the file")
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("got %d files, want the temporary file removed", len(entries))
+	}
+}
@@ -0,0 +1,224 @@
+// Package anthropic adapts the Anthropic Messages API to llm.Provider.
+package anthropic
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	sdk "github.com/anthropics/anthropic-sdk-go"
+	"github.com/anthropics/anthropic-sdk-go/option"
+
+	"github.com/jacygao/ai/llm"
+)
+
+const (
+	DefaultModel     = sdk.ModelClaude3_7SonnetLatest
+	defaultMaxTokens = 1024
+)
+
+type Provider struct {
+	client *sdk.Client
+	model  string
+}
+
+// New creates a provider using the API key from ANTHROPIC_API_KEY unless one
+// is passed in opts. An empty model selects DefaultModel.
+func New(model string, opts ...option.RequestOption) *Provider {
+	client := sdk.NewClient(opts...)
+	if model == "" {
+		model = string(DefaultModel)
+	}
+	return &Provider{client: &client, model: model}
+}
+
+func (p *Provider) Chat(ctx context.Context, req llm.Request) (*llm.Response, error) {
+	params, err := p.params(req)
+	if err != nil {
+		return nil, err
+	}
+
+	message, err := p.client.Messages.New(ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("anthropic: %w", err)
+	}
+	return toResponse(message), nil
+}
+
+func (p *Provider) ChatStream(ctx context.Context, req llm.Request, fn llm.StreamFunc) (*llm.Response, error) {
+	params, err := p.params(req)
+	if err != nil {
+		return nil, err
+	}
+
+	stream := p.client.Messages.NewStreaming(ctx, params)
+	defer stream.Close()
+
+	message := sdk.Message{}
+	for stream.Next() {
+		event := stream.Current()
+		if err := message.Accumulate(event); err != nil {
+			return nil, fmt.Errorf("anthropic: %w", err)
+		}
+		if event.Type == "content_block_delta" && event.Delta.Type == "text_delta" {
+			if err := fn(event.Delta.Text); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := stream.Err(); err != nil {
+		return nil, fmt.Errorf("anthropic: %w", err)
+	}
+	return toResponse(&message), nil
+}
+
+// Embed is not supported: Anthropic does not offer an embeddings API.
+func (p *Provider) Embed(ctx context.Context, input []string) ([][]float64, error) {
+	return nil, llm.ErrNotSupported
+}
+
+func (p *Provider) params(req llm.Request) (sdk.MessageNewParams, error) {
+	model := req.Model
+	if model == "" {
+		model = p.model
+	}
+	maxTokens := req.MaxTokens
+	if maxTokens <= 0 {
+		maxTokens = defaultMaxTokens
+	}
+
+	params := sdk.MessageNewParams{
+		Model:         sdk.Model(model),
+		MaxTokens:     int64(maxTokens),
+		StopSequences: req.Stop,
+	}
+	if req.Temperature != nil {
+		params.Temperature = sdk.Float(*req.Temperature)
+	}
+
+	for _, tool := range req.Tools {
+		params.Tools = append(params.Tools, sdk.ToolUnionParam{
+			OfTool: &sdk.ToolParam{
+				Name:        tool.Name,
+				Description: sdk.String(tool.Description),
+				InputSchema: inputSchema(tool.Parameters),
+			},
+		})
+	}
+
+	messages, system, err := toMessages(req.Messages)
+	if err != nil {
+		return params, err
+	}
+	params.Messages = messages
+	params.System = system
+	return params, nil
+}
+
+func inputSchema(parameters map[string]any) sdk.ToolInputSchemaParam {
+	schema := sdk.ToolInputSchemaParam{
+		Properties:  parameters["properties"],
+		ExtraFields: map[string]any{},
+	}
+	if required, ok := parameters["required"].([]string); ok {
+		schema.Required = required
+	}
+	if required, ok := parameters["required"].([]any); ok {
+		for _, name := range required {
+			if s, ok := name.(string); ok {
+				schema.Required = append(schema.Required, s)
+			}
+		}
+	}
+	for key, value := range parameters {
+		if key != "type" && key != "properties" && key != "required" {
+			schema.ExtraFields[key] = value
+		}
+	}
+	return schema
+}
+
+// toMessages converts the conversation into Anthropic messages. System
+// messages move to the system prompt, and consecutive tool results are merged
+// into a single user message as the API requires.
+func toMessages(messages []llm.Message) ([]sdk.MessageParam, []sdk.TextBlockParam, error) {
+	var out []sdk.MessageParam
+	var system []sdk.TextBlockParam
+
+	for _, m := range messages {
+		switch m.Role {
+		case llm.RoleSystem:
+			system = append(system, sdk.TextBlockParam{Text: m.Content})
+		case llm.RoleUser:
+			out = append(out, sdk.NewUserMessage(sdk.NewTextBlock(m.Content)))
+		case llm.RoleAssistant:
+			blocks := []sdk.ContentBlockParamUnion{}
+			if m.Content != "" {
+				blocks = append(blocks, sdk.NewTextBlock(m.Content))
+			}
+			for _, call := range m.ToolCalls {
+				var input any = map[string]any{}
+				if len(call.Arguments) > 0 {
+					if err := json.Unmarshal(call.Arguments, &input); err != nil {
+						return nil, nil, fmt.Errorf("anthropic: tool call %s has invalid arguments: %w", call.ID, err)
+					}
+				}
+				blocks = append(blocks, sdk.NewToolUseBlock(call.ID, input, call.Name))
+			}
+			out = append(out, sdk.NewAssistantMessage(blocks...))
+		case llm.RoleTool:
+			block := sdk.NewToolResultBlock(m.ToolCallID, m.Content, m.IsError)
+			if n := len(out); n > 0 && out[n-1].Role == sdk.MessageParamRoleUser && isToolResults(out[n-1]) {
+				out[n-1].Content = append(out[n-1].Content, block)
+				continue
+			}
+			out = append(out, sdk.NewUserMessage(block))
+		default:
+			return nil, nil, fmt.Errorf("anthropic: unsupported role %q", m.Role)
+		}
+	}
+
+	return out, system, nil
+}
+
+func isToolResults(m sdk.MessageParam) bool {
+	for _, block := range m.Content {
+		if block.OfToolResult == nil {
+			return false
+		}
+	}
+	return len(m.Content) > 0
+}
+
+func toResponse(message *sdk.Message) *llm.Response {
+	response := &llm.Response{
+		Message: llm.Message{Role: llm.RoleAssistant},
+		Usage: llm.Usage{
+			InputTokens:  int(message.Usage.InputTokens),
+			OutputTokens: int(message.Usage.OutputTokens),
+		},
+	}
+
+	for _, content := range message.Content {
+		switch content.Type {
+		case "text":
+			response.Message.Content += content.Text
+		case "tool_use":
+			response.Message.ToolCalls = append(response.Message.ToolCalls, llm.ToolCall{
+				ID:        content.ID,
+				Name:      content.Name,
+				Arguments: content.Input,
+			})
+		}
+	}
+
+	switch message.StopReason {
+	case sdk.StopReasonToolUse:
+		response.StopReason = llm.StopToolUse
+	case sdk.StopReasonMaxTokens:
+		response.StopReason = llm.StopMaxTokens
+	default:
+		response.StopReason = llm.StopEnd
+	}
+	return response
+}
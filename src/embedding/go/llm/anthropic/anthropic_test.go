@@ -0,0 +1,139 @@
+package anthropic
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/anthropics/anthropic-sdk-go/option"
+
+	"github.com/jacygao/ai/llm"
+)
+
+func TestToMessages(t *testing.T) {
+	messages, system, err := toMessages([]llm.Message{
+		llm.SystemMessage("Be brief."),
+		llm.UserMessage("Weather in Sydney and Perth?"),
+		{
+			Role: llm.RoleAssistant,
+			ToolCalls: []llm.ToolCall{
+				{ID: "toolu_1", Name: "get_weather", Arguments: json.RawMessage(`{"location":"Sydney"}`)},
+				{ID: "toolu_2", Name: "get_weather", Arguments: json.RawMessage(`{"location":"Perth"}`)},
+			},
+		},
+		llm.ToolMessage("toolu_1", "Sunny", false),
+		llm.ToolMessage("toolu_2", "station offline", true),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(system) != 1 || system[0].Text != "Be brief." {
+		t.Errorf("got system %+v, want the system message", system)
+	}
+	if len(messages) != 3 {
+		t.Fatalf("got %d messages, want user, assistant and one merged tool result message", len(messages))
+	}
+	if got := len(messages[1].Content); got != 2 {
+		t.Errorf("got %d assistant blocks, want 2 tool uses", got)
+	}
+	results := messages[2].Content
+	if len(results) != 2 || results[0].OfToolResult == nil || results[1].OfToolResult == nil {
+		t.Fatalf("got %+v, want two tool results in one message", results)
+	}
+	if results[0].OfToolResult.ToolUseID != "toolu_1" || !results[1].OfToolResult.IsError.Value {
+		t.Errorf("got tool results %+v, %+v", results[0].OfToolResult, results[1].OfToolResult)
+	}
+}
+
+func TestToMessagesInvalidArguments(t *testing.T) {
+	_, _, err := toMessages([]llm.Message{{
+		Role:      llm.RoleAssistant,
+		ToolCalls: []llm.ToolCall{{ID: "toolu_1", Name: "get_weather", Arguments: json.RawMessage(`{`)}},
+	}})
+	if err == nil {
+		t.Error("got no error for invalid tool arguments")
+	}
+}
+
+func TestChat(t *testing.T) {
+	var request map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/messages" {
+			http.NotFound(w, r)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{
+			"id": "msg_1",
+			"type": "message",
+			"role": "assistant",
+			"model": "claude-test",
+			"content": [
+				{"type": "text", "text": "Let me check."},
+				{"type": "tool_use", "id": "toolu_1", "name": "get_weather", "input": {"location": "Sydney"}}
+			],
+			"stop_reason": "tool_use",
+			"usage": {"input_tokens": 12, "output_tokens": 7}
+		}`))
+	}))
+	defer server.Close()
+
+	provider := New("claude-test", option.WithBaseURL(server.URL), option.WithAPIKey("test"), option.WithMaxRetries(0))
+	response, err := provider.Chat(context.Background(), llm.Request{
+		Messages: []llm.Message{llm.SystemMessage("Be brief."), llm.UserMessage("Weather in Sydney?")},
+		Tools: []llm.Tool{{
+			Name:        "get_weather",
+			Description: "Get weather at the given location",
+			Parameters: map[string]any{
+				"type":       "object",
+				"properties": map[string]any{"location": map[string]any{"type": "string"}},
+				"required":   []string{"location"},
+			},
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if request["model"] != "claude-test" || request["max_tokens"] != float64(defaultMaxTokens) {
+		t.Errorf("got model %v max_tokens %v", request["model"], request["max_tokens"])
+	}
+	tools, _ := request["tools"].([]any)
+	if len(tools) != 1 || tools[0].(map[string]any)["name"] != "get_weather" {
+		t.Errorf("got tools %v, want get_weather", request["tools"])
+	}
+	if system, _ := request["system"].([]any); len(system) != 1 {
+		t.Errorf("got system %v, want one text block", request["system"])
+	}
+
+	if response.Message.Content != "Let me check." {
+		t.Errorf("got content %q", response.Message.Content)
+	}
+	if response.StopReason != llm.StopToolUse {
+		t.Errorf("got stop reason %q, want %q", response.StopReason, llm.StopToolUse)
+	}
+	calls := response.Message.ToolCalls
+	if len(calls) != 1 || calls[0].ID != "toolu_1" || calls[0].Name != "get_weather" {
+		t.Fatalf("got tool calls %+v", calls)
+	}
+	var args struct{ Location string }
+	if err := json.Unmarshal(calls[0].Arguments, &args); err != nil || args.Location != "Sydney" {
+		t.Errorf("got arguments %s, %v", calls[0].Arguments, err)
+	}
+	if response.Usage != (llm.Usage{InputTokens: 12, OutputTokens: 7}) {
+		t.Errorf("got usage %+v", response.Usage)
+	}
+}
+
+func TestEmbedNotSupported(t *testing.T) {
+	if _, err := New("").Embed(context.Background(), []string{"text"}); err != llm.ErrNotSupported {
+		t.Errorf("got error %v, want %v", err, llm.ErrNotSupported)
+	}
+}
@@ -0,0 +1,164 @@
+// Package cite parses the [n] citation markers of RAG answers and checks them
+// against the passages that were actually given to the model.
+package cite
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/jacygao/ai/llm/prompt"
+)
+
+var (
+	markerPattern        = regexp.MustCompile(`\[(\d+)\]`)
+	leadingMarkerPattern = regexp.MustCompile(`^\s*\[\d+\]`)
+	spaceBeforePunct     = regexp.MustCompile(`\s+([.!?,;:])`)
+)
+
+// Citation is a passage referenced by the answer.
+type Citation struct {
+	// Number is the passage number used in the marker, e.g. 2 for [2].
+	Number int
+	// Valid is false when no passage with that number was retrieved.
+	Valid bool
+	// Passage is the cited passage, zero when the citation is not valid.
+	Passage prompt.Passage
+}
+
+// Sentence is one sentence of the answer with the passages it cites.
+type Sentence struct {
+	Text      string
+	Citations []int
+	// Supported is true when the sentence cites at least one retrieved passage.
+	Supported bool
+}
+
+// Answer is a model answer with its citations resolved.
+type Answer struct {
+	// Text is the answer as generated, including the citation markers.
+	Text string
+	// Passages are the passages the prompt contained, which may be fewer
+	// than were retrieved when the prompt had to fit a token budget.
+	Passages []prompt.Passage
+	// Citations lists every distinct passage number cited, in order of first use.
+	Citations []Citation
+	Sentences []Sentence
+}
+
+// Parse splits the answer into sentences and resolves their citation markers
+// against the passages the prompt contained, matched by Passage.Number.
+func Parse(text string, passages []prompt.Passage) *Answer {
+	byNumber := make(map[int]prompt.Passage, len(passages))
+	for _, p := range passages {
+		byNumber[p.Number] = p
+	}
+
+	answer := &Answer{Text: text, Passages: passages}
+	seen := make(map[int]bool)
+
+	for _, raw := range splitSentences(text) {
+		clean := markerPattern.ReplaceAllString(raw, "")
+		clean = spaceBeforePunct.ReplaceAllString(clean, "$1")
+		sentence := Sentence{Text: strings.TrimSpace(clean)}
+		if sentence.Text == "" {
+			continue
+		}
+
+		for _, match := range markerPattern.FindAllStringSubmatch(raw, -1) {
+			number, err := strconv.Atoi(match[1])
+			if err != nil {
+				continue
+			}
+			passage, valid := byNumber[number]
+			sentence.Citations = append(sentence.Citations, number)
+			if valid {
+				sentence.Supported = true
+			}
+			if !seen[number] {
+				seen[number] = true
+				answer.Citations = append(answer.Citations, Citation{Number: number, Valid: valid, Passage: passage})
+			}
+		}
+		answer.Sentences = append(answer.Sentences, sentence)
+	}
+
+	return answer
+}
+
+// splitSentences splits text after . ! or ? when followed by whitespace or
+// the end of the text, and at line breaks. Citation markers right after the
+// punctuation, as in "engineer. [1]", stay with the sentence they follow.
+func splitSentences(text string) []string {
+	var sentences []string
+	add := func(s string) {
+		if strings.TrimSpace(s) != "" {
+			sentences = append(sentences, s)
+		}
+	}
+
+	start := 0
+	for i := 0; i < len(text); i++ {
+		switch text[i] {
+		case '\n':
+			add(text[start:i])
+			start = i + 1
+		case '.', '!', '?':
+			end := i + 1
+			for end < len(text) && strings.IndexByte(".!?", text[end]) >= 0 {
+				end++
+			}
+			for {
+				loc := leadingMarkerPattern.FindStringIndex(text[end:])
+				if loc == nil {
+					break
+				}
+				end += loc[1]
+			}
+			if end == len(text) || unicode.IsSpace(rune(text[end])) {
+				add(text[start:end])
+				start = end
+				i = end - 1
+			}
+		}
+	}
+	add(text[start:])
+
+	return sentences
+}
+
+// Unsupported returns the sentences that do not cite any retrieved passage.
+func (a *Answer) Unsupported() []Sentence {
+	var unsupported []Sentence
+	for _, s := range a.Sentences {
+		if !s.Supported {
+			unsupported = append(unsupported, s)
+		}
+	}
+	return unsupported
+}
+
+// InvalidCitations returns the cited numbers that match no retrieved passage.
+func (a *Answer) InvalidCitations() []int {
+	var invalid []int
+	for _, c := range a.Citations {
+		if !c.Valid {
+			invalid = append(invalid, c.Number)
+		}
+	}
+	sort.Ints(invalid)
+	return invalid
+}
+
+// Sources returns the cited passages that were retrieved, in order of first use.
+func (a *Answer) Sources() []prompt.Passage {
+	var sources []prompt.Passage
+	for _, c := range a.Citations {
+		if c.Valid {
+			sources = append(sources, c.Passage)
+		}
+	}
+	return sources
+}
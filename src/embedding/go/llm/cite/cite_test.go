@@ -0,0 +1,179 @@
+package cite
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jacygao/ai/llm/prompt"
+)
+
+func TestSplitSentences(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{name: "empty", text: "", want: nil},
+		{name: "one sentence", text: "Charlotte is a spider.", want: []string{"Charlotte is a spider."}},
+		{name: "no final punctuation", text: "Charlotte is a spider", want: []string{"Charlotte is a spider"}},
+		{
+			name: "every end of sentence",
+			text: "Is it? Yes! It is.",
+			want: []string{"Is it?", " Yes!", " It is."},
+		},
+		{
+			name: "repeated punctuation",
+			text: "Really?! Yes...",
+			want: []string{"Really?!", " Yes..."},
+		},
+		{
+			name: "no split inside words and numbers",
+			text: "Version 1.5 of example.com is out.",
+			want: []string{"Version 1.5 of example.com is out."},
+		},
+		{
+			name: "markers after the punctuation",
+			text: "Jacy is an engineer. [1][2] Wilbur is a pig.[3]",
+			want: []string{"Jacy is an engineer. [1][2]", " Wilbur is a pig.[3]"},
+		},
+		{
+			name: "line breaks",
+			text: "- Charlotte [1]\n\n- Wilbur [2]\n",
+			want: []string{"- Charlotte [1]", "- Wilbur [2]"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := splitSentences(test.text); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	passages := []prompt.Passage{
+		{Number: 1, ID: "charlotte", Text: "Charlotte is a spider."},
+		{Number: 2, ID: "wilbur", Text: "Wilbur is a pig."},
+		{Number: 3, ID: "fern", Text: "Fern saves Wilbur."},
+	}
+
+	tests := []struct {
+		name      string
+		text      string
+		sentences []Sentence
+		citations []int
+		invalid   []int
+	}{
+		{
+			name: "one citation per sentence",
+			text: "Charlotte is a spider [1]. Wilbur is a pig [2].",
+			sentences: []Sentence{
+				{Text: "Charlotte is a spider.", Citations: []int{1}, Supported: true},
+				{Text: "Wilbur is a pig.", Citations: []int{2}, Supported: true},
+			},
+			citations: []int{1, 2},
+		},
+		{
+			name: "several citations in order of first use",
+			text: "Wilbur is saved [3][2]. Charlotte helps [1][3].",
+			sentences: []Sentence{
+				{Text: "Wilbur is saved.", Citations: []int{3, 2}, Supported: true},
+				{Text: "Charlotte helps.", Citations: []int{1, 3}, Supported: true},
+			},
+			citations: []int{3, 2, 1},
+		},
+		{
+			name: "markers after the punctuation",
+			text: "Charlotte is a spider. [1] Wilbur is a pig.[2]",
+			sentences: []Sentence{
+				{Text: "Charlotte is a spider.", Citations: []int{1}, Supported: true},
+				{Text: "Wilbur is a pig.", Citations: []int{2}, Supported: true},
+			},
+			citations: []int{1, 2},
+		},
+		{
+			name: "out of range numbers",
+			text: "Templeton is a rat [4]. Fern is a girl [0][3].",
+			sentences: []Sentence{
+				{Text: "Templeton is a rat.", Citations: []int{4}},
+				{Text: "Fern is a girl.", Citations: []int{0, 3}, Supported: true},
+			},
+			citations: []int{4, 0, 3},
+			invalid:   []int{0, 4},
+		},
+		{
+			name: "uncited sentences",
+			text: "Charlotte is a spider [1]. She is clever. Wilbur is a pig.",
+			sentences: []Sentence{
+				{Text: "Charlotte is a spider.", Citations: []int{1}, Supported: true},
+				{Text: "She is clever."},
+				{Text: "Wilbur is a pig."},
+			},
+			citations: []int{1},
+		},
+		{
+			name:      "markers only",
+			text:      "[1] [2]",
+			sentences: nil,
+		},
+		{
+			name:      "refusal",
+			text:      "I do not have any knowledge to answer that question.",
+			sentences: []Sentence{{Text: "I do not have any knowledge to answer that question."}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			answer := Parse(test.text, passages)
+			if answer.Text != test.text || !reflect.DeepEqual(answer.Passages, passages) {
+				t.Errorf("got text %q and passages %v, want %q and the passages parsed with", answer.Text, answer.Passages, test.text)
+			}
+			if !reflect.DeepEqual(answer.Sentences, test.sentences) {
+				t.Errorf("got sentences %+v, want %+v", answer.Sentences, test.sentences)
+			}
+
+			var citations []int
+			for _, c := range answer.Citations {
+				citations = append(citations, c.Number)
+				want := c.Number >= 1 && c.Number <= len(passages)
+				if c.Valid != want {
+					t.Errorf("got citation [%d] valid %t, want %t", c.Number, c.Valid, want)
+				}
+				if c.Valid && c.Passage.ID != passages[c.Number-1].ID {
+					t.Errorf("got citation [%d] of passage %q, want %q", c.Number, c.Passage.ID, passages[c.Number-1].ID)
+				}
+			}
+			if !reflect.DeepEqual(citations, test.citations) {
+				t.Errorf("got citations %v, want %v", citations, test.citations)
+			}
+			if got := answer.InvalidCitations(); !reflect.DeepEqual(got, test.invalid) {
+				t.Errorf("got invalid citations %v, want %v", got, test.invalid)
+			}
+		})
+	}
+}
+
+func TestAnswerMethods(t *testing.T) {
+	passages := []prompt.Passage{
+		{Number: 1, ID: "charlotte"},
+		{Number: 2, ID: "wilbur"},
+	}
+	answer := Parse("Wilbur is a pig [2][5]. He is some pig. Charlotte is a spider [1][2].", passages)
+
+	var unsupported []string
+	for _, s := range answer.Unsupported() {
+		unsupported = append(unsupported, s.Text)
+	}
+	if want := []string{"He is some pig."}; !reflect.DeepEqual(unsupported, want) {
+		t.Errorf("got unsupported %q, want %q", unsupported, want)
+	}
+
+	var sources []string
+	for _, p := range answer.Sources() {
+		sources = append(sources, p.ID)
+	}
+	if want := []string{"wilbur", "charlotte"}; !reflect.DeepEqual(sources, want) {
+		t.Errorf("got sources %q, want %q", sources, want)
+	}
+}
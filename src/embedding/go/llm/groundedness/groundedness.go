@@ -0,0 +1,199 @@
+// Package groundedness classifies RAG answers as answered, refused or
+// suspected hallucinations, by checking them against the retrieved context.
+package groundedness
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/jacygao/ai/llm"
+	"github.com/jacygao/ai/llm/cite"
+	"github.com/jacygao/ai/llm/prompt"
+)
+
+// RefusalAnswer is the exact answer the prompt templates ask for when the
+// context does not contain the answer.
+const RefusalAnswer = "I do not have any knowledge to answer that question"
+
+type Verdict string
+
+const (
+	Answered             Verdict = "answered"
+	Refused              Verdict = "refused"
+	HallucinationSuspect Verdict = "hallucination-suspect"
+)
+
+var judgePrompt = "You are checking whether an answer is supported by a context.\n" +
+	"Rate from 0 to 10 how much of the answer within the <answer> tags is supported by the context within the <context> tags, " +
+	"where 0 means none of it is and 10 means every claim is stated in the context.\n" +
+	"Respond with the number only.\n" +
+	"<question>\n%s\n</question>\n" +
+	"<context>\n%s</context>\n" +
+	"<answer>\n%s\n</answer>\n"
+
+// Result is the outcome of a check. Scores are in [0, 1].
+type Result struct {
+	Verdict Verdict `json:"verdict"`
+	// Confidence is how sure the checker is of the verdict.
+	Confidence float64 `json:"confidence"`
+	// Overlap is the share of the answer's content words found in the context,
+	// 1 when the answer has no content words and so claims nothing.
+	Overlap float64 `json:"overlap"`
+	// JudgeScore is the LLM judge's rating, nil when no judge is configured.
+	JudgeScore *float64 `json:"judge_score,omitempty"`
+	// Unsupported lists the answer sentences with too little overlap.
+	Unsupported []string `json:"unsupported,omitempty"`
+}
+
+// Checker scores answers by word overlap with the context and, when Judge is
+// set, by asking a model to rate them. The final score is the mean of the
+// signals used, and answers scoring below Threshold are suspect.
+type Checker struct {
+	// Judge is an optional model used as a second opinion, e.g. an ollama Client.
+	Judge llm.Chatter
+	// Threshold is the score below which an answer is suspect, and
+	// SentenceThreshold the overlap below which a sentence is unsupported.
+	Threshold         float64
+	SentenceThreshold float64
+	// RefusalPhrases detect refusals: an answer is one when it is, or starts
+	// with, one of the phrases, compared case-insensitively. Answers merely
+	// mentioning a phrase are not refusals.
+	RefusalPhrases []string
+}
+
+func NewChecker(judge llm.Chatter) *Checker {
+	return &Checker{
+		Judge:             judge,
+		Threshold:         0.5,
+		SentenceThreshold: 0.5,
+		RefusalPhrases: []string{
+			RefusalAnswer,
+			"i don't have any knowledge to answer that question",
+			"the context does not contain",
+		},
+	}
+}
+
+// Check classifies the answer to question given the passages it was
+// generated from, numbered as they were in the prompt.
+func (c *Checker) Check(ctx context.Context, question string, answer string, passages []prompt.Passage) (*Result, error) {
+	if c.isRefusal(answer) {
+		return &Result{Verdict: Refused, Confidence: 1}, nil
+	}
+
+	contextWords := make(map[string]bool)
+	for _, p := range passages {
+		for _, w := range contentWords(p.Text) {
+			contextWords[w] = true
+		}
+	}
+
+	result := &Result{}
+	found, total := 0, 0
+	for _, sentence := range cite.Parse(answer, passages).Sentences {
+		words := contentWords(sentence.Text)
+		if len(words) == 0 {
+			continue
+		}
+		sentenceFound := 0
+		for _, w := range words {
+			if contextWords[w] {
+				sentenceFound++
+			}
+		}
+		found += sentenceFound
+		total += len(words)
+		if float64(sentenceFound)/float64(len(words)) < c.SentenceThreshold {
+			result.Unsupported = append(result.Unsupported, sentence.Text)
+		}
+	}
+	result.Overlap = 1
+	if total > 0 {
+		result.Overlap = float64(found) / float64(total)
+	}
+
+	score := result.Overlap
+	if c.Judge != nil {
+		judgeScore, err := c.judge(ctx, question, answer, passages)
+		if err != nil {
+			return nil, err
+		}
+		result.JudgeScore = &judgeScore
+		score = (score + judgeScore) / 2
+	}
+
+	if score < c.Threshold {
+		result.Verdict = HallucinationSuspect
+		result.Confidence = 1 - score
+	} else {
+		result.Verdict = Answered
+		result.Confidence = score
+	}
+	return result, nil
+}
+
+func (c *Checker) isRefusal(answer string) bool {
+	normalized := normalize(answer)
+	for _, phrase := range c.RefusalPhrases {
+		phrase = strings.TrimRight(normalize(phrase), ".!")
+		if phrase != "" && strings.HasPrefix(normalized, phrase) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalize lowercases text and collapses its whitespace.
+func normalize(text string) string {
+	return strings.ToLower(strings.Join(strings.Fields(text), " "))
+}
+
+func (c *Checker) judge(ctx context.Context, question string, answer string, passages []prompt.Passage) (float64, error) {
+	var numbered strings.Builder
+	for _, p := range passages {
+		fmt.Fprintf(&numbered, "[%d] %s\n", p.Number, p.Text)
+	}
+
+	zero := 0.0
+	resp, err := c.Judge.Chat(ctx, llm.Request{
+		Messages:    []llm.Message{llm.UserMessage(fmt.Sprintf(judgePrompt, question, numbered.String(), answer))},
+		Temperature: &zero,
+		MaxTokens:   8,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("groundedness: judge: %w", err)
+	}
+
+	score, err := llm.ParseScore(resp.Message.Content, 10)
+	if err != nil {
+		return 0, fmt.Errorf("groundedness: judge: %w", err)
+	}
+	return score, nil
+}
+
+// stopwords are ignored when measuring overlap, since they say nothing about
+// whether a claim comes from the context.
+var stopwords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true, "but": true,
+	"by": true, "for": true, "from": true, "has": true, "have": true, "he": true, "her": true, "his": true,
+	"in": true, "is": true, "it": true, "its": true, "of": true, "on": true, "or": true, "she": true,
+	"that": true, "the": true, "their": true, "they": true, "this": true, "to": true, "was": true,
+	"were": true, "will": true, "with": true,
+}
+
+func contentWords(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r) && r != '\''
+	})
+
+	var content []string
+	for _, w := range words {
+		w = strings.TrimSuffix(strings.Trim(w, "'"), "'s")
+		if w != "" && !stopwords[w] {
+			content = append(content, w)
+		}
+	}
+	return content
+}
@@ -0,0 +1,204 @@
+package groundedness
+
+import (
+	"context"
+	"errors"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jacygao/ai/llm"
+	"github.com/jacygao/ai/llm/llmtest"
+	"github.com/jacygao/ai/llm/prompt"
+)
+
+var passages = []prompt.Passage{
+	{Number: 1, Text: "Charlotte is a spider who lives in the barn."},
+	{Number: 2, Text: "Wilbur is a pig. Fern saved Wilbur from slaughter."},
+}
+
+func TestCheckOverlap(t *testing.T) {
+	tests := []struct {
+		name        string
+		answer      string
+		verdict     Verdict
+		overlap     float64
+		confidence  float64
+		unsupported []string
+	}{
+		{
+			name:       "every word in the context",
+			answer:     "Charlotte is a spider [1].",
+			verdict:    Answered,
+			overlap:    1,
+			confidence: 1,
+		},
+		{
+			name:       "possessives and case",
+			answer:     "CHARLOTTE's barn.",
+			verdict:    Answered,
+			overlap:    1,
+			confidence: 1,
+		},
+		{
+			name:        "one unsupported sentence",
+			answer:      "Wilbur is a pig who lives in the barn. Templeton is a rat.",
+			verdict:     Answered,
+			overlap:     5.0 / 7,
+			confidence:  5.0 / 7,
+			unsupported: []string{"Templeton is a rat."},
+		},
+		{
+			name:        "mostly unsupported",
+			answer:      "Charlotte is a spider. She writes words in her web.",
+			verdict:     HallucinationSuspect,
+			overlap:     2.0 / 5,
+			confidence:  3.0 / 5,
+			unsupported: []string{"She writes words in her web."},
+		},
+		{
+			name:        "nothing supported",
+			answer:      "Templeton is a rat.",
+			verdict:     HallucinationSuspect,
+			overlap:     0,
+			confidence:  1,
+			unsupported: []string{"Templeton is a rat."},
+		},
+		{
+			name:       "no content words",
+			answer:     "It is. [1]",
+			verdict:    Answered,
+			overlap:    1,
+			confidence: 1,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := NewChecker(nil).Check(context.Background(), "Who is Charlotte?", test.answer, passages)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Verdict != test.verdict {
+				t.Errorf("got verdict %s, want %s", result.Verdict, test.verdict)
+			}
+			if math.Abs(result.Overlap-test.overlap) > 1e-9 {
+				t.Errorf("got overlap %v, want %v", result.Overlap, test.overlap)
+			}
+			if math.Abs(result.Confidence-test.confidence) > 1e-9 {
+				t.Errorf("got confidence %v, want %v", result.Confidence, test.confidence)
+			}
+			if result.JudgeScore != nil {
+				t.Errorf("got judge score %v without a judge", *result.JudgeScore)
+			}
+			if !reflect.DeepEqual(result.Unsupported, test.unsupported) {
+				t.Errorf("got unsupported %q, want %q", result.Unsupported, test.unsupported)
+			}
+		})
+	}
+}
+
+func TestCheckRefusal(t *testing.T) {
+	tests := []struct {
+		answer  string
+		refused bool
+	}{
+		{answer: RefusalAnswer + ".", refused: true},
+		{answer: RefusalAnswer, refused: true},
+		{answer: "  i do NOT have any\nknowledge to answer  that question!", refused: true},
+		{answer: "I don't have any knowledge to answer that question.", refused: true},
+		{answer: "The context does not contain Charlotte's age.", refused: true},
+		{answer: RefusalAnswer + ". Charlotte is a spider though.", refused: true},
+		{answer: "Charlotte is a spider, but the context does not contain her age."},
+		{answer: "Charlotte said: I do not have any knowledge to answer that question."},
+		{answer: "Charlotte is a spider."},
+	}
+	for _, test := range tests {
+		fake := llmtest.NewFake("10")
+		checker := NewChecker(fake)
+
+		result, err := checker.Check(context.Background(), "How old is Charlotte?", test.answer, passages)
+		if err != nil {
+			t.Fatalf("%q: %v", test.answer, err)
+		}
+		if refused := result.Verdict == Refused; refused != test.refused {
+			t.Errorf("%q: got verdict %s, want refused %t", test.answer, result.Verdict, test.refused)
+		}
+		if test.refused && (result.Confidence != 1 || len(fake.Requests()) != 0) {
+			t.Errorf("%q: got confidence %v after %d judge calls, want 1 and none", test.answer, result.Confidence, len(fake.Requests()))
+		}
+	}
+}
+
+func TestCheckJudge(t *testing.T) {
+	// Passages are numbered as in the prompt, not by their position
+	numbered := []prompt.Passage{
+		{Number: 2, Text: "Charlotte is a spider who lives in the barn."},
+		{Number: 4, Text: "Wilbur is a pig."},
+	}
+	tests := []struct {
+		name       string
+		answer     string
+		reply      string
+		verdict    Verdict
+		judge      float64
+		confidence float64
+	}{
+		{name: "both agree", answer: "Charlotte is a spider [2].", reply: "8", verdict: Answered, judge: 0.8, confidence: 0.9},
+		{name: "judge disagrees", answer: "Charlotte is a spider [2].", reply: "0", verdict: Answered, judge: 0, confidence: 0.5},
+		{name: "judge tips it", answer: "Charlotte is a spider. She writes words in her web.", reply: "Score: 1/10", verdict: HallucinationSuspect, judge: 0.1, confidence: 0.75},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fake := llmtest.NewFake(test.reply)
+			result, err := NewChecker(fake).Check(context.Background(), "Who is Charlotte?", test.answer, numbered)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Verdict != test.verdict {
+				t.Errorf("got verdict %s, want %s", result.Verdict, test.verdict)
+			}
+			if result.JudgeScore == nil || math.Abs(*result.JudgeScore-test.judge) > 1e-9 {
+				t.Errorf("got judge score %v, want %v", result.JudgeScore, test.judge)
+			}
+			if math.Abs(result.Confidence-test.confidence) > 1e-9 {
+				t.Errorf("got confidence %v, want %v", result.Confidence, test.confidence)
+			}
+
+			req := fake.Requests()[0]
+			if req.Temperature == nil || *req.Temperature != 0 || req.MaxTokens != 8 {
+				t.Errorf("got temperature %v and max tokens %d", req.Temperature, req.MaxTokens)
+			}
+			content := req.Messages[0].Content
+			for _, want := range []string{
+				"<question>\nWho is Charlotte?\n</question>",
+				"<context>\n[2] Charlotte is a spider who lives in the barn.\n[4] Wilbur is a pig.\n</context>",
+				"<answer>\n" + test.answer + "\n</answer>",
+			} {
+				if !strings.Contains(content, want) {
+					t.Errorf("judge prompt does not contain %q:\n%s", want, content)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckJudgeErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		judge *llmtest.Fake
+	}{
+		{name: "model error", judge: &llmtest.Fake{Respond: func(llm.Request) (*llm.Response, error) {
+			return nil, errors.New("model unavailable")
+		}}},
+		{name: "no score", judge: llmtest.NewFake("supported")},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := NewChecker(test.judge).Check(context.Background(), "Who is Charlotte?", "Charlotte is a spider.", passages)
+			if err == nil || !strings.HasPrefix(err.Error(), "groundedness: judge: ") || result != nil {
+				t.Errorf("got %v, %v, want a judge error", result, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+// Package llm defines a provider-agnostic interface for chat and embedding
+// models, so agents and RAG programs can switch between Anthropic, OpenAI and
+// Ollama without changing their own code.
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+)
+
+// ErrNotSupported is returned by providers that do not implement an operation,
+// for example embeddings on Anthropic.
+var ErrNotSupported = errors.New("llm: operation not supported by provider")
+
+type Role string
+
+const (
+	RoleSystem    Role = "system"
+	RoleUser      Role = "user"
+	RoleAssistant Role = "assistant"
+	RoleTool      Role = "tool"
+)
+
+// Message is a single entry of a conversation.
+type Message struct {
+	Role    Role
+	Content string
+
+	// ToolCalls is set on assistant messages that ask for tools to be run.
+	ToolCalls []ToolCall
+	// ToolCallID is set on tool messages and refers to the call being answered.
+	ToolCallID string
+	// IsError marks a tool message whose content describes a failure.
+	IsError bool
+}
+
+func SystemMessage(content string) Message {
+	return Message{Role: RoleSystem, Content: content}
+}
+
+func UserMessage(content string) Message {
+	return Message{Role: RoleUser, Content: content}
+}
+
+func AssistantMessage(content string) Message {
+	return Message{Role: RoleAssistant, Content: content}
+}
+
+func ToolMessage(toolCallID string, content string, isError bool) Message {
+	return Message{Role: RoleTool, ToolCallID: toolCallID, Content: content, IsError: isError}
+}
+
+// ToolCall is a request from the model to run a tool.
+type ToolCall struct {
+	ID        string
+	Name      string
+	Arguments json.RawMessage
+}
+
+// Tool describes a function the model may call. Parameters is a JSON Schema
+// object describing the arguments.
+type Tool struct {
+	Name        string
+	Description string
+	Parameters  map[string]any
+}
+
+type StopReason string
+
+const (
+	StopEnd       StopReason = "end"
+	StopToolUse   StopReason = "tool_use"
+	StopMaxTokens StopReason = "max_tokens"
+)
+
+type Request struct {
+	// Model overrides the provider's default chat model when set.
+	Model    string
+	Messages []Message
+	Tools    []Tool
+
+	MaxTokens   int
+	Temperature *float64
+	Seed        *int64
+	Stop        []string
+}
+
+type Usage struct {
+	InputTokens  int
+	OutputTokens int
+}
+
+type Response struct {
+	Message    Message
+	StopReason StopReason
+	Usage      Usage
+}
+
+// StreamFunc receives text as the model generates it. Returning an error
+// aborts the stream.
+type StreamFunc func(text string) error
+
+type Chatter interface {
+	Chat(ctx context.Context, req Request) (*Response, error)
+	// ChatStream behaves like Chat but calls fn with every text delta as it
+	// arrives. The returned Response holds the complete message.
+	ChatStream(ctx context.Context, req Request, fn StreamFunc) (*Response, error)
+}
+
+type Embedder interface {
+	// Embed returns one vector per input, in input order.
+	Embed(ctx context.Context, input []string) ([][]float64, error)
+}
+
+// Provider is an LLM backend that can chat and embed.
+type Provider interface {
+	Chatter
+	Embedder
+}
@@ -0,0 +1,123 @@
+// Package llmtest provides a scripted in-memory llm.Provider for tests and
+// offline runs.
+package llmtest
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"sync"
+
+	"github.com/jacygao/ai/llm"
+)
+
+// Fake replays Responses in order, one per Chat or ChatStream call, and
+// records every request it receives. Embeddings are derived
+// deterministically from the input text.
+type Fake struct {
+	Responses []*llm.Response
+	// Respond, when set, answers every request instead of Responses, for
+	// callers whose requests arrive in no particular order.
+	Respond func(req llm.Request) (*llm.Response, error)
+	// Dim is the size of the vectors returned by Embed, 8 when zero.
+	Dim int
+
+	mu       sync.Mutex
+	requests []llm.Request
+	next     int
+}
+
+// NewFake returns a fake that answers with the given assistant replies.
+func NewFake(replies ...string) *Fake {
+	f := &Fake{}
+	for _, reply := range replies {
+		f.Responses = append(f.Responses, Reply(reply))
+	}
+	return f
+}
+
+// Reply is a response that ends the turn with content.
+func Reply(content string) *llm.Response {
+	return &llm.Response{
+		Message:    llm.AssistantMessage(content),
+		StopReason: llm.StopEnd,
+	}
+}
+
+// CallTools is a response that asks for the given tool calls.
+func CallTools(calls ...llm.ToolCall) *llm.Response {
+	return &llm.Response{
+		Message:    llm.Message{Role: llm.RoleAssistant, ToolCalls: calls},
+		StopReason: llm.StopToolUse,
+	}
+}
+
+// Requests returns the requests received so far.
+func (f *Fake) Requests() []llm.Request {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]llm.Request(nil), f.requests...)
+}
+
+func (f *Fake) Chat(ctx context.Context, req llm.Request) (*llm.Response, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.requests = append(f.requests, req)
+	if f.Respond != nil {
+		return f.Respond(req)
+	}
+	if f.next >= len(f.Responses) {
+		return nil, fmt.Errorf("llmtest: no scripted response left for request %d", len(f.requests))
+	}
+	response := *f.Responses[f.next]
+	f.next++
+	return &response, nil
+}
+
+// ChatStream streams the scripted content word by word.
+func (f *Fake) ChatStream(ctx context.Context, req llm.Request, fn llm.StreamFunc) (*llm.Response, error) {
+	response, err := f.Chat(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	for _, word := range strings.SplitAfter(response.Message.Content, " ") {
+		if word == "" {
+			continue
+		}
+		if err := fn(word); err != nil {
+			return nil, err
+		}
+	}
+	return response, nil
+}
+
+// Embed returns a bag-of-words hash embedding of every input, so texts that
+// share words end up close to each other.
+func (f *Fake) Embed(ctx context.Context, input []string) ([][]float64, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	dim := f.Dim
+	if dim <= 0 {
+		dim = 8
+	}
+
+	vectors := make([][]float64, len(input))
+	for i, text := range input {
+		vector := make([]float64, dim)
+		for _, word := range strings.Fields(strings.ToLower(text)) {
+			h := fnv.New32a()
+			h.Write([]byte(word))
+			vector[h.Sum32()%uint32(dim)]++
+		}
+		vectors[i] = vector
+	}
+	return vectors, nil
+}
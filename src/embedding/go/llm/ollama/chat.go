@@ -0,0 +1,162 @@
+package ollama
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/jacygao/ai/llm"
+)
+
+// Client implements llm.Provider on top of /api/chat and /api/embed.
+var _ llm.Provider = (*Client)(nil)
+
+type chatMessage struct {
+	Role      string         `json:"role"`
+	Content   string         `json:"content"`
+	ToolCalls []chatToolCall `json:"tool_calls,omitempty"`
+}
+
+type chatToolCall struct {
+	Function chatFunctionCall `json:"function"`
+}
+
+type chatFunctionCall struct {
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+type chatTool struct {
+	Type     string       `json:"type"`
+	Function chatFunction `json:"function"`
+}
+
+type chatFunction struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	Parameters  map[string]any `json:"parameters"`
+}
+
+type chatRequest struct {
+	Model    string         `json:"model"`
+	Messages []chatMessage  `json:"messages"`
+	Tools    []chatTool     `json:"tools,omitempty"`
+	Stream   bool           `json:"stream"`
+	Options  map[string]any `json:"options,omitempty"`
+}
+
+type chatResponse struct {
+	Message         chatMessage `json:"message"`
+	Done            bool        `json:"done"`
+	DoneReason      string      `json:"done_reason"`
+	PromptEvalCount int         `json:"prompt_eval_count"`
+	EvalCount       int         `json:"eval_count"`
+	Error           string      `json:"error"`
+}
+
+func (c *Client) Chat(ctx context.Context, req llm.Request) (*llm.Response, error) {
+	return c.chat(ctx, req, nil)
+}
+
+func (c *Client) ChatStream(ctx context.Context, req llm.Request, fn llm.StreamFunc) (*llm.Response, error) {
+	return c.chat(ctx, req, fn)
+}
+
+func (c *Client) chat(ctx context.Context, req llm.Request, fn llm.StreamFunc) (*llm.Response, error) {
+	body := chatRequest{
+		Model:   req.Model,
+		Stream:  fn != nil,
+		Options: c.requestOptions(req),
+	}
+	if body.Model == "" {
+		body.Model = c.ChatModel
+	}
+	for _, tool := range req.Tools {
+		body.Tools = append(body.Tools, chatTool{
+			Type:     "function",
+			Function: chatFunction{Name: tool.Name, Description: tool.Description, Parameters: tool.Parameters},
+		})
+	}
+	for _, m := range req.Messages {
+		message := chatMessage{Role: string(m.Role), Content: m.Content}
+		for _, call := range m.ToolCalls {
+			message.ToolCalls = append(message.ToolCalls, chatToolCall{
+				Function: chatFunctionCall{Name: call.Name, Arguments: call.Arguments},
+			})
+		}
+		body.Messages = append(body.Messages, message)
+	}
+
+	resp, err := c.post(ctx, "/api/chat", body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// Ollama answers with one JSON object per line, the last one has done set
+	response := &llm.Response{Message: llm.Message{Role: llm.RoleAssistant}}
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var frame chatResponse
+		if err := json.Unmarshal(scanner.Bytes(), &frame); err != nil {
+			return nil, fmt.Errorf("ollama: error decoding response: %w", err)
+		}
+		if frame.Error != "" {
+			return nil, fmt.Errorf("ollama: %s", frame.Error)
+		}
+
+		response.Message.Content += frame.Message.Content
+		for _, call := range frame.Message.ToolCalls {
+			response.Message.ToolCalls = append(response.Message.ToolCalls, llm.ToolCall{
+				ID:        fmt.Sprintf("call_%d", len(response.Message.ToolCalls)),
+				Name:      call.Function.Name,
+				Arguments: call.Function.Arguments,
+			})
+		}
+		if fn != nil && frame.Message.Content != "" {
+			if err := fn(frame.Message.Content); err != nil {
+				return nil, err
+			}
+		}
+
+		if frame.Done {
+			response.Usage = llm.Usage{InputTokens: frame.PromptEvalCount, OutputTokens: frame.EvalCount}
+			switch {
+			case len(response.Message.ToolCalls) > 0:
+				response.StopReason = llm.StopToolUse
+			case frame.DoneReason == "length":
+				response.StopReason = llm.StopMaxTokens
+			default:
+				response.StopReason = llm.StopEnd
+			}
+			return response, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("ollama: error reading response: %w", err)
+	}
+	return nil, fmt.Errorf("ollama: response ended before done")
+}
+
+// requestOptions merges the per-request parameters over the client's options.
+func (c *Client) requestOptions(req llm.Request) map[string]any {
+	options := c.Options.toMap()
+	if req.Temperature != nil {
+		options["temperature"] = *req.Temperature
+	}
+	if req.Seed != nil {
+		options["seed"] = *req.Seed
+	}
+	if req.MaxTokens > 0 {
+		options["num_predict"] = req.MaxTokens
+	}
+	if len(req.Stop) > 0 {
+		options["stop"] = req.Stop
+	}
+	if len(options) == 0 {
+		return nil
+	}
+	return options
+}
@@ -0,0 +1,192 @@
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jacygao/ai/llm"
+)
+
+// newTestClient returns a client of a server that answers /api/chat with the
+// given newline delimited frames and records the request.
+func newTestClient(t *testing.T, frames ...string) (*Client, *chatRequest) {
+	t.Helper()
+	request := &chatRequest{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/chat" {
+			http.NotFound(w, r)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(request); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(strings.Join(frames, "\n") + "\n"))
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewClient()
+	client.BaseURL = server.URL
+	client.MaxRetries = 0
+	return client, request
+}
+
+func TestChatStream(t *testing.T) {
+	client, request := newTestClient(t,
+		`{"message":{"role":"assistant","content":"Sunny "}}`,
+		`{"message":{"role":"assistant","content":"and warm."}}`,
+		`{"message":{"role":"assistant","content":""},"done":true,"done_reason":"stop","prompt_eval_count":11,"eval_count":4}`,
+	)
+
+	temperature := 0.2
+	var deltas []string
+	response, err := client.ChatStream(context.Background(), llm.Request{
+		Messages:    []llm.Message{llm.SystemMessage("Be brief."), llm.UserMessage("Weather?")},
+		Temperature: &temperature,
+		MaxTokens:   64,
+	}, func(text string) error {
+		deltas = append(deltas, text)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !request.Stream || request.Model != DefaultChatModel {
+		t.Errorf("got stream %v model %q, want a streamed request to %q", request.Stream, request.Model, DefaultChatModel)
+	}
+	if request.Options["temperature"] != 0.2 || request.Options["num_predict"] != float64(64) {
+		t.Errorf("got options %v", request.Options)
+	}
+	if want := []string{"Sunny ", "and warm."}; !reflect.DeepEqual(deltas, want) {
+		t.Errorf("got deltas %q, want %q", deltas, want)
+	}
+	if response.Message.Content != "Sunny and warm." || response.StopReason != llm.StopEnd {
+		t.Errorf("got %q stopped by %q", response.Message.Content, response.StopReason)
+	}
+	if response.Usage != (llm.Usage{InputTokens: 11, OutputTokens: 4}) {
+		t.Errorf("got usage %+v", response.Usage)
+	}
+}
+
+func TestChatToolCalls(t *testing.T) {
+	client, request := newTestClient(t,
+		`{"message":{"role":"assistant","content":"","tool_calls":[{"function":{"name":"get_weather","arguments":{"location":"Sydney"}}}]},"done":true,"done_reason":"stop"}`,
+	)
+
+	response, err := client.Chat(context.Background(), llm.Request{
+		Messages: []llm.Message{llm.UserMessage("Weather in Sydney?")},
+		Tools:    []llm.Tool{{Name: "get_weather", Description: "Get weather", Parameters: map[string]any{"type": "object"}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if request.Stream || len(request.Tools) != 1 || request.Tools[0].Type != "function" {
+		t.Errorf("got stream %v tools %+v", request.Stream, request.Tools)
+	}
+	if response.StopReason != llm.StopToolUse {
+		t.Errorf("got stop reason %q, want %q", response.StopReason, llm.StopToolUse)
+	}
+	calls := response.Message.ToolCalls
+	if len(calls) != 1 || calls[0].ID != "call_0" || calls[0].Name != "get_weather" || string(calls[0].Arguments) != `{"location":"Sydney"}` {
+		t.Errorf("got tool calls %+v", calls)
+	}
+}
+
+func TestChatStopReasons(t *testing.T) {
+	tests := []struct {
+		name   string
+		frames []string
+		want   llm.StopReason
+		err    string
+	}{
+		{
+			name:   "length",
+			frames: []string{`{"message":{"content":"cut"},"done":true,"done_reason":"length"}`},
+			want:   llm.StopMaxTokens,
+		},
+		{
+			name:   "error frame",
+			frames: []string{`{"error":"model not found"}`},
+			err:    "ollama: model not found",
+		},
+		{
+			name:   "not done",
+			frames: []string{`{"message":{"content":"partial"}}`},
+			err:    "ollama: response ended before done",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client, _ := newTestClient(t, test.frames...)
+			response, err := client.Chat(context.Background(), llm.Request{Messages: []llm.Message{llm.UserMessage("hi")}})
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Errorf("got error %v, want %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if response.StopReason != test.want {
+				t.Errorf("got stop reason %q, want %q", response.StopReason, test.want)
+			}
+		})
+	}
+}
+
+func TestChatInvalidFrame(t *testing.T) {
+	client, _ := newTestClient(t, `{"message":{"content":"ok"}}`, `not json`)
+	if _, err := client.Chat(context.Background(), llm.Request{Messages: []llm.Message{llm.UserMessage("hi")}}); err == nil || !strings.HasPrefix(err.Error(), "ollama: error decoding response") {
+		t.Errorf("got error %v, want a decoding error", err)
+	}
+}
+
+func TestChatStreamCancel(t *testing.T) {
+	// The server sends one frame and then stalls until the client goes away
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"message":{"role":"assistant","content":"Sunny"}}` + "\n"))
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	t.Cleanup(server.Close)
+	client := NewClient()
+	client.BaseURL = server.URL
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var deltas []string
+	_, err := client.ChatStream(ctx, llm.Request{Messages: []llm.Message{llm.UserMessage("Weather?")}}, func(text string) error {
+		deltas = append(deltas, text)
+		cancel()
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("got error %v, want %v", err, context.Canceled)
+	}
+	if !reflect.DeepEqual(deltas, []string{"Sunny"}) {
+		t.Errorf("got deltas %q, want the frame sent before cancelling", deltas)
+	}
+}
+
+func TestChatStreamCallbackError(t *testing.T) {
+	client, _ := newTestClient(t,
+		`{"message":{"content":"Sunny"}}`,
+		`{"message":{"content":""},"done":true}`,
+	)
+	stop := errors.New("stop")
+	_, err := client.ChatStream(context.Background(), llm.Request{Messages: []llm.Message{llm.UserMessage("hi")}}, func(string) error {
+		return stop
+	})
+	if !errors.Is(err, stop) {
+		t.Errorf("got error %v, want the callback error", err)
+	}
+}
@@ -0,0 +1,195 @@
+package ollama
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+)
+
+const (
+	DefaultBaseURL    = "http://localhost:11434"
+	DefaultChatModel  = "llama3.2"
+	DefaultEmbedModel = "all-minilm:l6-v2"
+)
+
+// GenerateOptions are the model parameters sent as "options" with every
+// chat request. Zero values leave Ollama's defaults in place.
+type GenerateOptions struct {
+	Temperature *float64
+	NumCtx      int
+	Seed        *int64
+	Stop        []string
+}
+
+func (o GenerateOptions) toMap() map[string]any {
+	options := map[string]any{}
+	if o.Temperature != nil {
+		options["temperature"] = *o.Temperature
+	}
+	if o.NumCtx > 0 {
+		options["num_ctx"] = o.NumCtx
+	}
+	if o.Seed != nil {
+		options["seed"] = *o.Seed
+	}
+	if len(o.Stop) > 0 {
+		options["stop"] = o.Stop
+	}
+	return options
+}
+
+// Client talks to an Ollama server. The zero value is not usable, create one
+// with NewClient and adjust its fields before the first call.
+type Client struct {
+	BaseURL    string
+	ChatModel  string
+	EmbedModel string
+	HTTPClient *http.Client
+
+	// Timeout bounds embedding requests. Chat requests are bounded by
+	// the caller's context only, since a long answer can legitimately take
+	// minutes.
+	Timeout time.Duration
+	// MaxRetries is how many times a request is retried after a connection
+	// error, a 429 or a 5xx response. RetryBackoff is the delay before the
+	// first retry and doubles on every attempt.
+	MaxRetries   int
+	RetryBackoff time.Duration
+
+	Options GenerateOptions
+
+	// EmbedBatchSize is the most inputs sent in one /api/embed request by
+	// EmbedBatch, and EmbedWorkers how many of those requests run at once.
+	EmbedBatchSize int
+	EmbedWorkers   int
+
+	// RerankModel is used to score passages in Rerank, ChatModel when empty.
+	// RerankWorkers is how many passages are scored concurrently.
+	RerankModel   string
+	RerankWorkers int
+}
+
+func NewClient() *Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{Timeout: 10 * time.Second}).DialContext
+	// Loading a model on first use can take a while before the first byte
+	transport.ResponseHeaderTimeout = 2 * time.Minute
+
+	return &Client{
+		BaseURL:        DefaultBaseURL,
+		ChatModel:      DefaultChatModel,
+		EmbedModel:     DefaultEmbedModel,
+		HTTPClient:     &http.Client{Transport: transport},
+		Timeout:        30 * time.Second,
+		MaxRetries:     2,
+		RetryBackoff:   500 * time.Millisecond,
+		EmbedBatchSize: 64,
+		EmbedWorkers:   4,
+		RerankWorkers:  4,
+	}
+}
+
+// DefaultClient is used by the package-level functions.
+var DefaultClient = NewClient()
+
+// Embed returns the embedding of every input, in input order. Large inputs are
+// split into concurrent requests, see EmbedBatch.
+func (c *Client) Embed(ctx context.Context, input []string) ([][]float64, error) {
+	return c.EmbedBatch(ctx, input)
+}
+
+// embed sends all inputs in a single /api/embed request.
+func (c *Client) embed(ctx context.Context, input []string) ([][]float64, error) {
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+
+	resp, err := c.post(ctx, "/api/embed", map[string]any{
+		"model": c.EmbedModel,
+		"input": input,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	vectors := &EmbedResponseBody{}
+	if err := json.NewDecoder(resp.Body).Decode(vectors); err != nil {
+		return nil, fmt.Errorf("ollama: error decoding embeddings: %w", err)
+	}
+	if len(vectors.Data) != len(input) {
+		return nil, fmt.Errorf("ollama: got %d embeddings for %d inputs", len(vectors.Data), len(input))
+	}
+	return vectors.Data, nil
+}
+
+// post sends payload as JSON and returns the response once a 200 status has
+// been received, retrying transient failures. The caller closes the body.
+func (c *Client) post(ctx context.Context, path string, payload any) (*http.Response, error) {
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("ollama: error marshaling payload: %w", err)
+	}
+
+	backoff := c.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		resp, err := c.send(ctx, path, jsonData)
+		if err == nil {
+			return resp, nil
+		}
+
+		var retryable *retryableError
+		if !errors.As(err, &retryable) || attempt >= c.MaxRetries {
+			return nil, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("ollama: %w", ctx.Err())
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+func (c *Client) send(ctx context.Context, path string, jsonData []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.BaseURL+path, bytes.NewReader(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("ollama: error creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("ollama: %w", err)
+		}
+		return nil, &retryableError{fmt.Errorf("ollama: %w", err)}
+	}
+	if resp.StatusCode == http.StatusOK {
+		return resp, nil
+	}
+
+	defer resp.Body.Close()
+	msg, _ := io.ReadAll(resp.Body)
+	err = fmt.Errorf("ollama: unexpected status %s: %s", resp.Status, bytes.TrimSpace(msg))
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+		return nil, &retryableError{err}
+	}
+	return nil, err
+}
+
+type retryableError struct {
+	err error
+}
+
+func (e *retryableError) Error() string { return e.err.Error() }
+func (e *retryableError) Unwrap() error { return e.err }
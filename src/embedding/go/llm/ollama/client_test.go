@@ -0,0 +1,101 @@
+package ollama
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newRetryClient returns a client of a server answering every request with
+// the next of statuses, repeating the last one, and the count of requests.
+func newRetryClient(t *testing.T, statuses ...int) (*Client, *atomic.Int32) {
+	t.Helper()
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(requests.Add(1))
+		status := statuses[min(n, len(statuses))-1]
+		if status != http.StatusOK {
+			http.Error(w, http.StatusText(status), status)
+			return
+		}
+		w.Write([]byte(`{"embeddings":[[1,0]]}`))
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewClient()
+	client.BaseURL = server.URL
+	client.RetryBackoff = time.Millisecond
+	return client, &requests
+}
+
+func TestPostRetries(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []int
+		requests int32
+		err      string
+	}{
+		{name: "ok", statuses: []int{200}, requests: 1},
+		{name: "429 then ok", statuses: []int{429, 200}, requests: 2},
+		{name: "5xx then ok", statuses: []int{503, 500, 200}, requests: 3},
+		{name: "4xx is not retried", statuses: []int{400, 200}, requests: 1, err: "ollama: unexpected status 400 Bad Request: Bad Request"},
+		{name: "retries run out", statuses: []int{502}, requests: 3, err: "ollama: unexpected status 502 Bad Gateway: Bad Gateway"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client, requests := newRetryClient(t, test.statuses...)
+			client.MaxRetries = 2
+
+			vectors, err := client.embed(context.Background(), []string{"text"})
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Errorf("got error %v, want %q", err, test.err)
+				}
+			} else if err != nil || len(vectors) != 1 {
+				t.Errorf("got %v, %v", vectors, err)
+			}
+			if got := requests.Load(); got != test.requests {
+				t.Errorf("got %d requests, want %d", got, test.requests)
+			}
+		})
+	}
+}
+
+func TestPostRetriesConnectionErrors(t *testing.T) {
+	client := NewClient()
+	// Nothing listens on a closed server's address
+	server := httptest.NewServer(http.NotFoundHandler())
+	client.BaseURL = server.URL
+	server.Close()
+	client.RetryBackoff = time.Millisecond
+	client.MaxRetries = 1
+
+	if _, err := client.embed(context.Background(), []string{"text"}); err == nil || !strings.HasPrefix(err.Error(), "ollama: ") {
+		t.Errorf("got error %v, want a connection error", err)
+	}
+}
+
+func TestPostCancelledDuringBackoff(t *testing.T) {
+	client, requests := newRetryClient(t, 503)
+	client.RetryBackoff = time.Hour
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, err := client.post(ctx, "/api/embed", map[string]any{})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("got error %v, want %v", err, context.Canceled)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("returned after %s, want right after cancelling", elapsed)
+	}
+	if got := requests.Load(); got != 1 {
+		t.Errorf("got %d requests, want 1", got)
+	}
+}
@@ -0,0 +1,69 @@
+package ollama
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// EmbedBatch embeds any number of inputs by splitting them into chunks of at
+// most EmbedBatchSize strings and sending up to EmbedWorkers chunks at once.
+// The vectors are returned in input order. The first failing chunk cancels
+// the remaining ones.
+func (c *Client) EmbedBatch(ctx context.Context, input []string) ([][]float64, error) {
+	batchSize := c.EmbedBatchSize
+	if batchSize <= 0 {
+		batchSize = len(input)
+	}
+	workers := c.EmbedWorkers
+	if workers <= 0 {
+		workers = 1
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	vectors := make([][]float64, len(input))
+	sem := make(chan struct{}, workers)
+
+	var wg sync.WaitGroup
+	var once sync.Once
+	var firstErr error
+
+	for start := 0; start < len(input); start += batchSize {
+		end := min(start+batchSize, len(input))
+
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func(start, end int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			chunk, err := c.embed(ctx, input[start:end])
+			if err != nil {
+				once.Do(func() {
+					firstErr = fmt.Errorf("ollama: error embedding inputs %d-%d: %w", start, end-1, err)
+					cancel()
+				})
+				return
+			}
+			copy(vectors[start:end], chunk)
+		}(start, end)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("ollama: %w", err)
+	}
+	return vectors, nil
+}
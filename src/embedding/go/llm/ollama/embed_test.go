@@ -0,0 +1,169 @@
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// embedServer embeds "input N" as the vector [N] after a random delay, so
+// chunks finish out of order. It records the size of every chunk and the
+// most requests it served at once.
+type embedServer struct {
+	mu       sync.Mutex
+	chunks   []int
+	inFlight int
+	peak     int
+}
+
+func (s *embedServer) client(t *testing.T, batchSize int, workers int) *Client {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Input []string `json:"input"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		s.mu.Lock()
+		s.chunks = append(s.chunks, len(body.Input))
+		s.inFlight++
+		s.peak = max(s.peak, s.inFlight)
+		s.mu.Unlock()
+		defer func() {
+			s.mu.Lock()
+			s.inFlight--
+			s.mu.Unlock()
+		}()
+
+		time.Sleep(time.Duration(rand.IntN(5)) * time.Millisecond)
+		vectors := make([][]float64, len(body.Input))
+		for i, input := range body.Input {
+			n, err := strconv.Atoi(strings.TrimPrefix(input, "input "))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			vectors[i] = []float64{float64(n)}
+		}
+		json.NewEncoder(w).Encode(map[string]any{"embeddings": vectors})
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewClient()
+	client.BaseURL = server.URL
+	client.MaxRetries = 0
+	client.EmbedBatchSize = batchSize
+	client.EmbedWorkers = workers
+	return client
+}
+
+func inputs(n int) ([]string, [][]float64) {
+	input := make([]string, n)
+	want := make([][]float64, n)
+	for i := range input {
+		input[i] = fmt.Sprintf("input %d", i)
+		want[i] = []float64{float64(i)}
+	}
+	return input, want
+}
+
+func TestEmbedBatch(t *testing.T) {
+	tests := []struct {
+		name      string
+		inputs    int
+		batchSize int
+		workers   int
+		chunks    []int
+	}{
+		{name: "partial last chunk", inputs: 10, batchSize: 3, workers: 3, chunks: []int{1, 3, 3, 3}},
+		{name: "exact chunks", inputs: 6, batchSize: 3, workers: 2, chunks: []int{3, 3}},
+		{name: "one per chunk", inputs: 5, batchSize: 1, workers: 4, chunks: []int{1, 1, 1, 1, 1}},
+		{name: "one chunk", inputs: 5, batchSize: 64, workers: 4, chunks: []int{5}},
+		{name: "no batch size", inputs: 5, batchSize: 0, workers: 0, chunks: []int{5}},
+		{name: "no inputs", inputs: 0, batchSize: 3, workers: 2, chunks: nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := &embedServer{}
+			client := server.client(t, test.batchSize, test.workers)
+			input, want := inputs(test.inputs)
+
+			vectors, err := client.EmbedBatch(context.Background(), input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(vectors, want) {
+				t.Errorf("got %v, want %v", vectors, want)
+			}
+			sort.Ints(server.chunks)
+			if !reflect.DeepEqual(server.chunks, test.chunks) {
+				t.Errorf("got chunks of %v, want %v", server.chunks, test.chunks)
+			}
+			if server.peak > max(test.workers, 1) {
+				t.Errorf("got %d requests at once, want at most %d", server.peak, test.workers)
+			}
+		})
+	}
+}
+
+func TestEmbedBatchCancelsOnFirstError(t *testing.T) {
+	var mu sync.Mutex
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Input []string `json:"input"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		mu.Lock()
+		requests++
+		mu.Unlock()
+
+		if body.Input[0] == "bad" {
+			http.Error(w, "invalid input", http.StatusBadRequest)
+			return
+		}
+		// Every other chunk hangs until the batch is cancelled
+		<-r.Context().Done()
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewClient()
+	client.BaseURL = server.URL
+	client.MaxRetries = 0
+	client.EmbedBatchSize = 2
+	client.EmbedWorkers = 2
+
+	input := []string{"slow", "slow", "bad", "bad", "slow", "slow", "slow", "slow", "slow", "slow"}
+	done := make(chan error, 1)
+	go func() {
+		_, err := client.EmbedBatch(context.Background(), input)
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if err == nil || !strings.HasPrefix(err.Error(), "ollama: error embedding inputs 2-3: ollama: unexpected status 400") {
+			t.Errorf("got error %v, want the error of the failing chunk", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("EmbedBatch did not return after a chunk failed")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if requests != 2 {
+		t.Errorf("got %d requests, want the remaining chunks not sent", requests)
+	}
+}
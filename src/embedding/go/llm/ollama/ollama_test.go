@@ -0,0 +1,21 @@
+package ollama
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGetPrompt(t *testing.T) {
+	rendered, err := GetPrompt("Who wrote it?", []string{"First passage.", "Second passage."})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"Who wrote it?", "First passage.", "Second passage."} {
+		if !strings.Contains(rendered, want) {
+			t.Errorf("prompt does not contain %q:\n%s", want, rendered)
+		}
+	}
+	if strings.Index(rendered, "First passage.") > strings.Index(rendered, "Second passage.") {
+		t.Error("passages are not in the given order")
+	}
+}
@@ -0,0 +1,86 @@
+package ollama
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/jacygao/ai/llm"
+)
+
+var rerankPrompt = "You are a search relevance judge.\n" +
+	"Rate how well the passage answers the question on a scale from 0 (irrelevant) to 10 (answers it completely).\n" +
+	"Respond with the number only.\n" +
+	"<question>\n%s\n</question>\n" +
+	"<passage>\n%s\n</passage>\n"
+
+const maxRerankScore = 10
+
+// RankedPassage is a candidate passage with its relevance score in [0, 1].
+type RankedPassage struct {
+	// Index is the position of the passage in the candidates passed to Rerank.
+	Index int
+	Text  string
+	Score float64
+}
+
+// Rerank asks RerankModel, or ChatModel when empty, to judge the relevance of
+// every candidate to the query and returns the candidates ordered from most
+// to least relevant. Ties keep their original order, so a retriever's
+// ranking is preserved where the model cannot tell passages apart.
+func (c *Client) Rerank(ctx context.Context, query string, candidates []string) ([]RankedPassage, error) {
+	return rerank(ctx, c, c.RerankModel, c.RerankWorkers, query, candidates)
+}
+
+// rerank scores the candidates with model on chatter, workers at a time.
+func rerank(ctx context.Context, chatter llm.Chatter, model string, workers int, query string, candidates []string) ([]RankedPassage, error) {
+	ranked := make([]RankedPassage, len(candidates))
+	errs := make([]error, len(candidates))
+
+	if workers <= 0 {
+		workers = 1
+	}
+	sem := make(chan struct{}, workers)
+
+	var wg sync.WaitGroup
+	for i, candidate := range candidates {
+		wg.Add(1)
+		go func(i int, candidate string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			score, err := scorePassage(ctx, chatter, model, query, candidate)
+			ranked[i] = RankedPassage{Index: i, Text: candidate, Score: score}
+			errs[i] = err
+		}(i, candidate)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("ollama: error scoring candidate %d: %w", i, err)
+		}
+	}
+
+	sort.SliceStable(ranked, func(a, b int) bool {
+		return ranked[a].Score > ranked[b].Score
+	})
+	return ranked, nil
+}
+
+func scorePassage(ctx context.Context, chatter llm.Chatter, model string, query string, passage string) (float64, error) {
+	// Deterministic and short: we only want a number back
+	temperature := 0.0
+	response, err := chatter.Chat(ctx, llm.Request{
+		Model:       model,
+		Messages:    []llm.Message{llm.UserMessage(fmt.Sprintf(rerankPrompt, query, passage))},
+		Temperature: &temperature,
+		MaxTokens:   8,
+	})
+	if err != nil {
+		return 0, err
+	}
+	return llm.ParseScore(response.Message.Content, maxRerankScore)
+}
@@ -0,0 +1,89 @@
+package ollama
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/jacygao/ai/llm"
+	"github.com/jacygao/ai/llm/llmtest"
+)
+
+// judge returns a fake that answers with the score given for the passage in
+// the prompt, or an error for passages without one.
+func judge(scores map[string]string) *llmtest.Fake {
+	return &llmtest.Fake{Respond: func(req llm.Request) (*llm.Response, error) {
+		prompt := req.Messages[0].Content
+		for passage, score := range scores {
+			if strings.Contains(prompt, "<passage>\n"+passage+"\n</passage>") {
+				return llmtest.Reply(score), nil
+			}
+		}
+		return nil, errors.New("model unavailable")
+	}}
+}
+
+func TestRerank(t *testing.T) {
+	fake := judge(map[string]string{
+		"Paris is in France.":             "2",
+		"The capital of France is Paris.": "10",
+		"France borders Spain.":           "2",
+		"Lyon is a city in France.":       "Score: 4/10",
+	})
+	candidates := []string{"Paris is in France.", "The capital of France is Paris.", "France borders Spain.", "Lyon is a city in France."}
+
+	ranked, err := rerank(context.Background(), fake, "judge", 2, "What is the capital of France?", candidates)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Ties keep the order of the candidates
+	want := []RankedPassage{
+		{Index: 1, Text: candidates[1], Score: 1},
+		{Index: 3, Text: candidates[3], Score: 0.4},
+		{Index: 0, Text: candidates[0], Score: 0.2},
+		{Index: 2, Text: candidates[2], Score: 0.2},
+	}
+	if len(ranked) != len(want) {
+		t.Fatalf("got %d passages, want %d", len(ranked), len(want))
+	}
+	for i := range want {
+		if ranked[i] != want[i] {
+			t.Errorf("rank %d: got %+v, want %+v", i+1, ranked[i], want[i])
+		}
+	}
+
+	for _, req := range fake.Requests() {
+		if req.Model != "judge" || req.MaxTokens != 8 || req.Temperature == nil || *req.Temperature != 0 {
+			t.Errorf("got request model %q max tokens %d temperature %v", req.Model, req.MaxTokens, req.Temperature)
+		}
+	}
+}
+
+func TestRerankErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		scores map[string]string
+		err    string
+	}{
+		{
+			name:   "model error",
+			scores: map[string]string{"a": "3"},
+			err:    "ollama: error scoring candidate 1: model unavailable",
+		},
+		{
+			name:   "no score in answer",
+			scores: map[string]string{"a": "3", "b": "relevant"},
+			err:    "ollama: error scoring candidate 1",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ranked, err := rerank(context.Background(), judge(test.scores), "", 1, "query", []string{"a", "b"})
+			if err == nil || !strings.HasPrefix(err.Error(), test.err) || ranked != nil {
+				t.Errorf("got %v, %v, want error %q", ranked, err, test.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package ollama
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jacygao/ai/llm"
+	"github.com/jacygao/ai/llm/cite"
+	"github.com/jacygao/ai/llm/prompt"
+)
+
+var rewritePrompt = "Given the conversation so far and a follow-up question, rewrite the follow-up question " +
+	"as a standalone question that can be understood without the conversation.\n" +
+	"Keep names and details from the conversation that the question refers to. " +
+	"If the question is already standalone, return it unchanged.\n" +
+	"Respond with the standalone question only.\n"
+
+const (
+	defaultMaxHistory    = 20
+	defaultContextTokens = 2048
+)
+
+// Session is a RAG conversation that remembers earlier questions and answers,
+// so follow-up questions can refer to them. It talks to the /api/chat
+// endpoint through any llm.Chatter, an ollama Client by default.
+type Session struct {
+	Chatter llm.Chatter
+	// History holds the previous questions and answers, without the context
+	// passages that were retrieved for them.
+	History []llm.Message
+	// MaxHistory is the most messages kept in History, older ones are dropped.
+	MaxHistory int
+
+	// Prompts and Template select the prompt each question is asked with.
+	// ContextTokens is the token budget of that prompt, passages that do not
+	// fit are dropped or truncated starting from the lowest ranked.
+	Prompts       *prompt.Set
+	Template      string
+	ContextTokens int
+}
+
+func NewSession(chatter llm.Chatter) *Session {
+	if chatter == nil {
+		chatter = DefaultClient
+	}
+	return &Session{
+		Chatter:       chatter,
+		MaxHistory:    defaultMaxHistory,
+		Prompts:       defaultPrompts,
+		Template:      prompt.CiteSources,
+		ContextTokens: defaultContextTokens,
+	}
+}
+
+// Rewrite turns a follow-up question into a standalone query suitable for
+// retrieval. The first question of a session is returned as is.
+func (s *Session) Rewrite(ctx context.Context, question string) (string, error) {
+	if len(s.History) == 0 {
+		return question, nil
+	}
+
+	var conversation strings.Builder
+	for _, m := range s.History {
+		fmt.Fprintf(&conversation, "%s: %s\n", m.Role, m.Content)
+	}
+
+	zero := 0.0
+	resp, err := s.Chatter.Chat(ctx, llm.Request{
+		Messages: []llm.Message{
+			llm.SystemMessage(rewritePrompt),
+			llm.UserMessage("<conversation>\n" + conversation.String() + "</conversation>\n" +
+				"<question>\n" + question + "\n</question>"),
+		},
+		Temperature: &zero,
+	})
+	if err != nil {
+		return "", fmt.Errorf("error rewriting question: %w", err)
+	}
+
+	standalone := strings.TrimSpace(resp.Message.Content)
+	if standalone == "" {
+		return question, nil
+	}
+	return standalone, nil
+}
+
+// Ask answers the question from the given passages, ordered from most to
+// least relevant, taking the earlier turns of the session into account, and
+// streams the answer to fn. The question and the answer are then added to
+// the history. The returned answer has its [n] citations resolved against
+// the passages that fit in the prompt.
+func (s *Session) Ask(ctx context.Context, question string, passages []prompt.Passage, fn llm.StreamFunc) (*cite.Answer, error) {
+	rendered, used, err := s.Prompts.Fit(s.Template, prompt.Data{Question: question, Passages: passages}, s.ContextTokens, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	messages := append([]llm.Message{}, s.History...)
+	messages = append(messages, llm.UserMessage(rendered))
+
+	resp, err := s.Chatter.ChatStream(ctx, llm.Request{Messages: messages}, fn)
+	if err != nil {
+		return nil, err
+	}
+
+	s.History = append(s.History, llm.UserMessage(question), llm.AssistantMessage(resp.Message.Content))
+	if s.MaxHistory > 0 && len(s.History) > s.MaxHistory {
+		s.History = s.History[len(s.History)-s.MaxHistory:]
+	}
+	return cite.Parse(resp.Message.Content, used), nil
+}
+
+// Reset forgets the conversation.
+func (s *Session) Reset() {
+	s.History = nil
+}
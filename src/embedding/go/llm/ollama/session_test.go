@@ -0,0 +1,178 @@
+package ollama
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/jacygao/ai/llm"
+	"github.com/jacygao/ai/llm/llmtest"
+	"github.com/jacygao/ai/llm/prompt"
+)
+
+func TestRewrite(t *testing.T) {
+	fake := llmtest.NewFake("  What did Charlotte weave?  ", "")
+	session := NewSession(fake)
+
+	standalone, err := session.Rewrite(context.Background(), "Who is Charlotte?")
+	if err != nil || standalone != "Who is Charlotte?" {
+		t.Errorf("got %q, %v, want the first question unchanged", standalone, err)
+	}
+	if got := len(fake.Requests()); got != 0 {
+		t.Errorf("got %d model calls for the first question, want none", got)
+	}
+
+	session.History = []llm.Message{llm.UserMessage("Who is Charlotte?"), llm.AssistantMessage("A spider.")}
+	standalone, err = session.Rewrite(context.Background(), "What did she weave?")
+	if err != nil || standalone != "What did Charlotte weave?" {
+		t.Errorf("got %q, %v, want the trimmed standalone question", standalone, err)
+	}
+	req := fake.Requests()[0]
+	if req.Temperature == nil || *req.Temperature != 0 {
+		t.Errorf("got temperature %v, want 0", req.Temperature)
+	}
+	content := req.Messages[len(req.Messages)-1].Content
+	for _, want := range []string{"user: Who is Charlotte?\n", "assistant: A spider.\n", "<question>\nWhat did she weave?\n</question>"} {
+		if !strings.Contains(content, want) {
+			t.Errorf("rewrite request does not contain %q:\n%s", want, content)
+		}
+	}
+
+	// An empty rewrite falls back to the question
+	standalone, err = session.Rewrite(context.Background(), "And then?")
+	if err != nil || standalone != "And then?" {
+		t.Errorf("got %q, %v, want the question for an empty rewrite", standalone, err)
+	}
+
+	// The fake has no responses left
+	if _, err := session.Rewrite(context.Background(), "Why?"); err == nil || !strings.HasPrefix(err.Error(), "error rewriting question: ") {
+		t.Errorf("got error %v, want a rewrite error", err)
+	}
+}
+
+func TestAskHistory(t *testing.T) {
+	fake := llmtest.NewFake("First answer.", "Second answer.", "Third answer.")
+	session := NewSession(fake)
+	session.MaxHistory = 4
+	passages := []prompt.Passage{{ID: "a", Text: "Wilbur is a pig."}}
+
+	for _, question := range []string{"First?", "Second?", "Third?"} {
+		if _, err := session.Ask(context.Background(), question, passages, func(string) error { return nil }); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The prompt of each question follows the plain questions and answers
+	// before it, without their passages
+	requests := fake.Requests()
+	second := requests[1].Messages
+	if len(second) != 3 || second[0].Content != "First?" || second[1].Content != "First answer." || !strings.Contains(second[2].Content, "Wilbur is a pig.") {
+		t.Errorf("got second request %+v", second)
+	}
+
+	want := []llm.Message{
+		llm.UserMessage("Second?"), llm.AssistantMessage("Second answer."),
+		llm.UserMessage("Third?"), llm.AssistantMessage("Third answer."),
+	}
+	if len(session.History) != len(want) {
+		t.Fatalf("got %d history messages, want %d", len(session.History), len(want))
+	}
+	for i := range want {
+		if session.History[i].Role != want[i].Role || session.History[i].Content != want[i].Content {
+			t.Errorf("history %d: got %+v, want %+v", i, session.History[i], want[i])
+		}
+	}
+
+	session.Reset()
+	if len(session.History) != 0 {
+		t.Errorf("got %d history messages after Reset, want none", len(session.History))
+	}
+	if standalone, err := session.Rewrite(context.Background(), "Fourth?"); err != nil || standalone != "Fourth?" {
+		t.Errorf("got %q, %v, want no rewrite after Reset", standalone, err)
+	}
+}
+
+func TestAskCitations(t *testing.T) {
+	fake := llmtest.NewFake("Charlotte is a spider [1]. She saves Wilbur [2][3]. She is clever.")
+	session := NewSession(fake)
+	passages := []prompt.Passage{
+		{ID: "charlotte", Text: "Charlotte is a spider."},
+		{ID: "wilbur", Text: "Charlotte saves Wilbur the pig."},
+	}
+
+	var streamed strings.Builder
+	answer, err := session.Ask(context.Background(), "Who is Charlotte?", passages, func(chunk string) error {
+		streamed.WriteString(chunk)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if streamed.String() != answer.Text {
+		t.Errorf("got streamed %q, want %q", streamed.String(), answer.Text)
+	}
+
+	// The default prompt asks for the citations
+	if content := fake.Requests()[0].Messages[0].Content; !strings.Contains(content, "cite the passages") {
+		t.Errorf("prompt does not ask for citations:\n%s", content)
+	}
+
+	var sources []string
+	for _, p := range answer.Sources() {
+		sources = append(sources, fmt.Sprintf("%d:%s", p.Number, p.ID))
+	}
+	if got, want := strings.Join(sources, " "), "1:charlotte 2:wilbur"; got != want {
+		t.Errorf("got sources %s, want %s", got, want)
+	}
+	if got := answer.InvalidCitations(); len(got) != 1 || got[0] != 3 {
+		t.Errorf("got invalid citations %v, want [3]", got)
+	}
+	if got := answer.Unsupported(); len(got) != 1 || got[0].Text != "She is clever." {
+		t.Errorf("got unsupported sentences %+v, want the last one", got)
+	}
+}
+
+func TestAskUsedPassages(t *testing.T) {
+	fake := llmtest.NewFake("Charlotte is a spider [1]. Wilbur is a pig [2].")
+	session := NewSession(fake)
+	passages := []prompt.Passage{
+		{ID: "charlotte", Text: "Charlotte is a spider."},
+		{ID: "wilbur", Text: strings.Repeat("Wilbur is a pig. ", 200)},
+	}
+	// Room for the first passage but not for a useful part of the second
+	base, _, err := session.Prompts.Fit(session.Template, prompt.Data{Question: "Who is Charlotte?"}, 100_000, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	session.ContextTokens = prompt.EstimateTokens(base) + 20
+
+	answer, err := session.Ask(context.Background(), "Who is Charlotte?", passages, func(string) error { return nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(answer.Passages) != 1 || answer.Passages[0].ID != "charlotte" || answer.Passages[0].Number != 1 {
+		t.Errorf("got passages %+v, want only the first one", answer.Passages)
+	}
+	if strings.Contains(fake.Requests()[0].Messages[0].Content, "Wilbur") {
+		t.Error("prompt contains the passage that did not fit")
+	}
+	if got := answer.InvalidCitations(); len(got) != 1 || got[0] != 2 {
+		t.Errorf("got invalid citations %v, want [2] of the dropped passage", got)
+	}
+}
+
+func TestAskErrorKeepsHistory(t *testing.T) {
+	session := NewSession(&llmtest.Fake{Respond: func(llm.Request) (*llm.Response, error) {
+		return nil, errors.New("model unavailable")
+	}})
+	session.History = []llm.Message{llm.UserMessage("First?"), llm.AssistantMessage("First answer.")}
+
+	if _, err := session.Ask(context.Background(), "Second?", nil, func(string) error { return nil }); err == nil {
+		t.Fatal("got no error")
+	}
+	if len(session.History) != 2 {
+		t.Errorf("got %d history messages, want the failed question left out", len(session.History))
+	}
+}
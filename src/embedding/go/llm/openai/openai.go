@@ -0,0 +1,193 @@
+// Package openai adapts the OpenAI Chat Completions and Embeddings APIs to
+// llm.Provider.
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	sdk "github.com/openai/openai-go"
+	"github.com/openai/openai-go/option"
+
+	"github.com/jacygao/ai/llm"
+)
+
+const (
+	DefaultModel      = sdk.ChatModelGPT4o
+	DefaultEmbedModel = sdk.EmbeddingModelTextEmbedding3Small
+)
+
+type Provider struct {
+	client     *sdk.Client
+	model      string
+	embedModel string
+}
+
+// New creates a provider using the API key from OPENAI_API_KEY unless one is
+// passed in opts. Empty model names select DefaultModel and DefaultEmbedModel.
+func New(model string, embedModel string, opts ...option.RequestOption) *Provider {
+	client := sdk.NewClient(opts...)
+	if model == "" {
+		model = DefaultModel
+	}
+	if embedModel == "" {
+		embedModel = DefaultEmbedModel
+	}
+	return &Provider{client: &client, model: model, embedModel: embedModel}
+}
+
+func (p *Provider) Chat(ctx context.Context, req llm.Request) (*llm.Response, error) {
+	params, err := p.params(req)
+	if err != nil {
+		return nil, err
+	}
+
+	completion, err := p.client.Chat.Completions.New(ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("openai: %w", err)
+	}
+	return toResponse(completion)
+}
+
+func (p *Provider) ChatStream(ctx context.Context, req llm.Request, fn llm.StreamFunc) (*llm.Response, error) {
+	params, err := p.params(req)
+	if err != nil {
+		return nil, err
+	}
+	params.StreamOptions = sdk.ChatCompletionStreamOptionsParam{IncludeUsage: sdk.Bool(true)}
+
+	stream := p.client.Chat.Completions.NewStreaming(ctx, params)
+	defer stream.Close()
+
+	acc := sdk.ChatCompletionAccumulator{}
+	for stream.Next() {
+		chunk := stream.Current()
+		acc.AddChunk(chunk)
+		if len(chunk.Choices) > 0 && chunk.Choices[0].Delta.Content != "" {
+			if err := fn(chunk.Choices[0].Delta.Content); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := stream.Err(); err != nil {
+		return nil, fmt.Errorf("openai: %w", err)
+	}
+	return toResponse(&acc.ChatCompletion)
+}
+
+func (p *Provider) Embed(ctx context.Context, input []string) ([][]float64, error) {
+	resp, err := p.client.Embeddings.New(ctx, sdk.EmbeddingNewParams{
+		Model: p.embedModel,
+		Input: sdk.EmbeddingNewParamsInputUnion{OfArrayOfStrings: input},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("openai: %w", err)
+	}
+
+	vectors := make([][]float64, len(input))
+	for _, data := range resp.Data {
+		if int(data.Index) < len(vectors) {
+			vectors[data.Index] = data.Embedding
+		}
+	}
+	return vectors, nil
+}
+
+func (p *Provider) params(req llm.Request) (sdk.ChatCompletionNewParams, error) {
+	model := req.Model
+	if model == "" {
+		model = p.model
+	}
+
+	params := sdk.ChatCompletionNewParams{
+		Model: model,
+	}
+	if req.MaxTokens > 0 {
+		params.MaxCompletionTokens = sdk.Int(int64(req.MaxTokens))
+	}
+	if req.Temperature != nil {
+		params.Temperature = sdk.Float(*req.Temperature)
+	}
+	if req.Seed != nil {
+		params.Seed = sdk.Int(*req.Seed)
+	}
+	if len(req.Stop) > 0 {
+		params.Stop = sdk.ChatCompletionNewParamsStopUnion{OfStringArray: req.Stop}
+	}
+
+	for _, tool := range req.Tools {
+		params.Tools = append(params.Tools, sdk.ChatCompletionToolParam{
+			Function: sdk.FunctionDefinitionParam{
+				Name:        tool.Name,
+				Description: sdk.String(tool.Description),
+				Parameters:  sdk.FunctionParameters(tool.Parameters),
+			},
+		})
+	}
+
+	for _, m := range req.Messages {
+		switch m.Role {
+		case llm.RoleSystem:
+			params.Messages = append(params.Messages, sdk.SystemMessage(m.Content))
+		case llm.RoleUser:
+			params.Messages = append(params.Messages, sdk.UserMessage(m.Content))
+		case llm.RoleAssistant:
+			assistant := sdk.ChatCompletionAssistantMessageParam{}
+			if m.Content != "" {
+				assistant.Content.OfString = sdk.String(m.Content)
+			}
+			for _, call := range m.ToolCalls {
+				assistant.ToolCalls = append(assistant.ToolCalls, sdk.ChatCompletionMessageToolCallParam{
+					ID: call.ID,
+					Function: sdk.ChatCompletionMessageToolCallFunctionParam{
+						Name:      call.Name,
+						Arguments: string(call.Arguments),
+					},
+				})
+			}
+			params.Messages = append(params.Messages, sdk.ChatCompletionMessageParamUnion{OfAssistant: &assistant})
+		case llm.RoleTool:
+			params.Messages = append(params.Messages, sdk.ToolMessage(m.Content, m.ToolCallID))
+		default:
+			return params, fmt.Errorf("openai: unsupported role %q", m.Role)
+		}
+	}
+
+	return params, nil
+}
+
+func toResponse(completion *sdk.ChatCompletion) (*llm.Response, error) {
+	if len(completion.Choices) == 0 {
+		return nil, fmt.Errorf("openai: completion returned no choices")
+	}
+	choice := completion.Choices[0]
+
+	response := &llm.Response{
+		Message: llm.Message{
+			Role:    llm.RoleAssistant,
+			Content: choice.Message.Content,
+		},
+		Usage: llm.Usage{
+			InputTokens:  int(completion.Usage.PromptTokens),
+			OutputTokens: int(completion.Usage.CompletionTokens),
+		},
+	}
+	for _, call := range choice.Message.ToolCalls {
+		response.Message.ToolCalls = append(response.Message.ToolCalls, llm.ToolCall{
+			ID:        call.ID,
+			Name:      call.Function.Name,
+			Arguments: json.RawMessage(call.Function.Arguments),
+		})
+	}
+
+	switch choice.FinishReason {
+	case "tool_calls":
+		response.StopReason = llm.StopToolUse
+	case "length":
+		response.StopReason = llm.StopMaxTokens
+	default:
+		response.StopReason = llm.StopEnd
+	}
+	return response, nil
+}
@@ -0,0 +1,147 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	sdk "github.com/openai/openai-go"
+	"github.com/openai/openai-go/option"
+
+	"github.com/jacygao/ai/llm"
+)
+
+func newTestProvider(t *testing.T, handler http.HandlerFunc) *Provider {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return New("gpt-test", "embed-test", option.WithBaseURL(server.URL), option.WithAPIKey("test"), option.WithMaxRetries(0))
+}
+
+func TestChat(t *testing.T) {
+	var request map[string]any
+	provider := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/chat/completions" {
+			http.NotFound(w, r)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{
+			"id": "chatcmpl-1",
+			"object": "chat.completion",
+			"model": "gpt-test",
+			"choices": [{
+				"index": 0,
+				"finish_reason": "tool_calls",
+				"message": {
+					"role": "assistant",
+					"content": "",
+					"tool_calls": [{"id": "call_1", "type": "function", "function": {"name": "get_weather", "arguments": "{\"location\":\"Sydney\"}"}}]
+				}
+			}],
+			"usage": {"prompt_tokens": 20, "completion_tokens": 9, "total_tokens": 29}
+		}`))
+	})
+
+	seed := int64(0)
+	response, err := provider.Chat(context.Background(), llm.Request{
+		Seed: &seed,
+		Messages: []llm.Message{
+			llm.SystemMessage("Be brief."),
+			llm.UserMessage("Weather in Sydney?"),
+			{Role: llm.RoleAssistant, ToolCalls: []llm.ToolCall{{ID: "call_0", Name: "get_weather", Arguments: json.RawMessage(`{"location":"Perth"}`)}}},
+			llm.ToolMessage("call_0", "Sunny", false),
+		},
+		Tools: []llm.Tool{{Name: "get_weather", Description: "Get weather at the given location", Parameters: map[string]any{"type": "object"}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if request["model"] != "gpt-test" || request["seed"] != float64(0) {
+		t.Errorf("got model %v seed %v", request["model"], request["seed"])
+	}
+	messages, _ := request["messages"].([]any)
+	var roles []string
+	for _, m := range messages {
+		roles = append(roles, m.(map[string]any)["role"].(string))
+	}
+	if want := []string{"system", "user", "assistant", "tool"}; !reflect.DeepEqual(roles, want) {
+		t.Errorf("got roles %v, want %v", roles, want)
+	}
+	if tool := messages[3].(map[string]any); tool["tool_call_id"] != "call_0" {
+		t.Errorf("got tool message %v, want it to answer call_0", tool)
+	}
+
+	if response.StopReason != llm.StopToolUse {
+		t.Errorf("got stop reason %q, want %q", response.StopReason, llm.StopToolUse)
+	}
+	want := []llm.ToolCall{{ID: "call_1", Name: "get_weather", Arguments: json.RawMessage(`{"location":"Sydney"}`)}}
+	if !reflect.DeepEqual(response.Message.ToolCalls, want) {
+		t.Errorf("got tool calls %+v, want %+v", response.Message.ToolCalls, want)
+	}
+	if response.Usage != (llm.Usage{InputTokens: 20, OutputTokens: 9}) {
+		t.Errorf("got usage %+v", response.Usage)
+	}
+}
+
+func TestToResponseStopReasons(t *testing.T) {
+	tests := map[string]llm.StopReason{
+		"stop":       llm.StopEnd,
+		"length":     llm.StopMaxTokens,
+		"tool_calls": llm.StopToolUse,
+	}
+	for finishReason, want := range tests {
+		response, err := toResponse(mustCompletion(t, `{"choices":[{"finish_reason":"`+finishReason+`","message":{"role":"assistant","content":"hi"}}]}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if response.StopReason != want {
+			t.Errorf("finish reason %s: got %q, want %q", finishReason, response.StopReason, want)
+		}
+	}
+
+	if _, err := toResponse(mustCompletion(t, `{"choices":[]}`)); err == nil {
+		t.Error("got no error for a completion without choices")
+	}
+}
+
+func mustCompletion(t *testing.T, data string) *sdk.ChatCompletion {
+	t.Helper()
+	completion := &sdk.ChatCompletion{}
+	if err := json.Unmarshal([]byte(data), completion); err != nil {
+		t.Fatal(err)
+	}
+	return completion
+}
+
+func TestEmbed(t *testing.T) {
+	provider := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		// Out of order, the adapter must place vectors by index
+		w.Write([]byte(`{
+			"object": "list",
+			"model": "embed-test",
+			"data": [
+				{"object": "embedding", "index": 1, "embedding": [0, 1]},
+				{"object": "embedding", "index": 0, "embedding": [1, 0]}
+			],
+			"usage": {"prompt_tokens": 2, "total_tokens": 2}
+		}`))
+	})
+
+	vectors, err := provider.Embed(context.Background(), []string{"first", "second"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := [][]float64{{1, 0}, {0, 1}}; !reflect.DeepEqual(vectors, want) {
+		t.Errorf("got %v, want %v", vectors, want)
+	}
+}
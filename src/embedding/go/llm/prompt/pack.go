@@ -0,0 +1,86 @@
+package prompt
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// TokenCounter returns the number of tokens a text takes in the model's
+// context window.
+type TokenCounter func(text string) int
+
+// EstimateTokens approximates the token count of English text at four
+// characters per token, which is close enough for budgeting without a
+// model-specific tokenizer.
+func EstimateTokens(text string) int {
+	return (utf8.RuneCountInString(text) + 3) / 4
+}
+
+// minTruncatedTokens is the smallest remainder worth filling with a truncated
+// passage, below it the passage is dropped instead.
+const minTruncatedTokens = 32
+
+// Fit renders the named template with as many passages as fit in budget
+// tokens. Passages must be ordered from most to least relevant: the lowest
+// ranked ones are dropped first, and the last one that partly fits is
+// truncated. It returns the prompt and the passages it contains.
+func (s *Set) Fit(name string, data Data, budget int, count TokenCounter) (string, []Passage, error) {
+	if count == nil {
+		count = EstimateTokens
+	}
+
+	passages := data.Passages
+	data.Passages = nil
+	base, err := s.Render(name, data)
+	if err != nil {
+		return "", nil, err
+	}
+	remaining := budget - count(base)
+	if remaining < 0 {
+		return "", nil, fmt.Errorf("prompt: template %q needs %d tokens without passages, budget is %d", name, count(base), budget)
+	}
+
+	for _, p := range passages {
+		data.Passages = append(data.Passages, p)
+		rendered, err := s.Render(name, data)
+		if err != nil {
+			return "", nil, err
+		}
+		used := count(rendered) - count(base)
+		if used <= remaining {
+			continue
+		}
+
+		// Fill what is left with the start of the passage, if worth it
+		data.Passages = data.Passages[:len(data.Passages)-1]
+		cost := used - count(p.Text)
+		if room := remaining - cost; room >= minTruncatedTokens {
+			p.Text = truncate(p.Text, room, count)
+			data.Passages = append(data.Passages, p)
+		}
+		break
+	}
+
+	prompt, err := s.Render(name, data)
+	if err != nil {
+		return "", nil, err
+	}
+	return prompt, numbered(data.Passages), nil
+}
+
+// truncate cuts text at a word boundary so that it takes at most budget
+// tokens, ellipsis included.
+func truncate(text string, budget int, count TokenCounter) string {
+	words := strings.Fields(text)
+	lo, hi := 0, len(words)
+	for lo < hi {
+		mid := (lo + hi + 1) / 2
+		if count(strings.Join(words[:mid], " ")+" ...") <= budget {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+	return strings.Join(words[:lo], " ") + " ..."
+}
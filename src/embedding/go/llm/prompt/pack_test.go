@@ -0,0 +1,139 @@
+package prompt
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// countWords counts every word as a token, so budgets can be worked out by
+// hand.
+func countWords(text string) int {
+	return len(strings.Fields(text))
+}
+
+// words returns a text of n words.
+func words(prefix string, n int) string {
+	w := make([]string, n)
+	for i := range w {
+		w[i] = fmt.Sprintf("%s%d", prefix, i)
+	}
+	return strings.Join(w, " ")
+}
+
+func TestFit(t *testing.T) {
+	set, err := Load(writeTemplates(t, map[string]string{
+		"fit.tmpl": "Question: {{.Question}}\n{{template \"passages\" .}}",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The prompt takes 2 tokens without passages and 41 more for each
+	// passage: its number and 40 words.
+	passages := []Passage{
+		{ID: "a", Text: words("a", 40)},
+		{ID: "b", Text: words("b", 40)},
+		{ID: "c", Text: words("c", 40)},
+	}
+
+	tests := []struct {
+		name   string
+		budget int
+		ids    []string
+		// truncated is the number of words kept of the last passage, 0 if
+		// it is whole
+		truncated int
+	}{
+		{name: "all fit", budget: 2 + 3*41, ids: []string{"a", "b", "c"}},
+		{name: "more than enough", budget: 1000, ids: []string{"a", "b", "c"}},
+		{name: "lowest ranked dropped", budget: 2 + 2*41, ids: []string{"a", "b"}},
+		{name: "last truncated", budget: 2 + 2*41 + 1 + 35, ids: []string{"a", "b", "c"}, truncated: 34},
+		{name: "remainder too small", budget: 2 + 2*41 + 1 + minTruncatedTokens - 1, ids: []string{"a", "b"}},
+		{name: "first truncated", budget: 2 + 1 + 33, ids: []string{"a"}, truncated: 32},
+		{name: "no passages fit", budget: 2 + 20, ids: nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			prompt, used, err := set.Fit("fit", Data{Question: "why", Passages: passages}, test.budget, countWords)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := countWords(prompt); got > test.budget {
+				t.Errorf("got a prompt of %d tokens, want at most %d", got, test.budget)
+			}
+
+			var ids []string
+			for i, p := range used {
+				ids = append(ids, p.ID)
+				if p.Number != i+1 {
+					t.Errorf("got passage %s numbered %d, want %d", p.ID, p.Number, i+1)
+				}
+				if !strings.Contains(prompt, fmt.Sprintf("[%d] %s\n", p.Number, p.Text)) {
+					t.Errorf("prompt does not contain passage %s as returned", p.ID)
+				}
+			}
+			if strings.Join(ids, ",") != strings.Join(test.ids, ",") {
+				t.Fatalf("got passages %v, want %v", ids, test.ids)
+			}
+
+			if len(used) == 0 {
+				return
+			}
+			last := used[len(used)-1]
+			want := passages[len(used)-1].Text
+			if test.truncated > 0 {
+				want = words(last.ID, test.truncated) + " ..."
+			}
+			if last.Text != want {
+				t.Errorf("got last passage %q, want %q", last.Text, want)
+			}
+		})
+	}
+}
+
+func TestFitDefaultCounter(t *testing.T) {
+	passages := make([]Passage, 10)
+	for i := range passages {
+		passages[i] = Passage{Text: words("word", 100)}
+	}
+	const budget = 1000
+
+	prompt, used, err := Default().Fit(StrictGrounded, Data{Question: "What?", Passages: passages}, budget, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := EstimateTokens(prompt); got > budget {
+		t.Errorf("got a prompt of %d tokens, want at most %d", got, budget)
+	}
+	if len(used) == 0 || len(used) == len(passages) {
+		t.Errorf("got %d of %d passages, want some dropped", len(used), len(passages))
+	}
+}
+
+func TestFitErrors(t *testing.T) {
+	if _, _, err := Default().Fit(StrictGrounded, Data{Question: "What?"}, 10, nil); err == nil ||
+		!strings.HasPrefix(err.Error(), `prompt: template "strict-grounded" needs`) {
+		t.Errorf("got error %v, want the template not to fit", err)
+	}
+	if _, _, err := Default().Fit("missing", Data{}, 1000, nil); err == nil {
+		t.Error("got no error for an unknown template")
+	}
+}
+
+func TestEstimateTokens(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"a", 1},
+		{"four", 1},
+		{"five!", 2},
+		{"héllo wörld", 3},
+	}
+	for _, test := range tests {
+		if got := EstimateTokens(test.text); got != test.want {
+			t.Errorf("EstimateTokens(%q): got %d, want %d", test.text, got, test.want)
+		}
+	}
+}
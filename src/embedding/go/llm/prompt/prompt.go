@@ -0,0 +1,134 @@
+// Package prompt renders RAG prompts from text/template files. Built-in
+// templates can be overridden or extended from a directory, so prompts can
+// be iterated on without recompiling.
+package prompt
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// Names of the built-in templates.
+const (
+	StrictGrounded = "strict-grounded"
+	Summarise      = "summarise"
+	CiteSources    = "cite-sources"
+)
+
+// Templates shared by the prompts rather than prompts themselves. "passages"
+// renders the numbered passages and is defined in partials.tmpl.
+var partials = map[string]bool{"partials": true, "passages": true}
+
+//go:embed templates/*.tmpl
+var builtin embed.FS
+
+// Passage is a retrieved piece of text rendered into the prompt.
+type Passage struct {
+	// Number is the 1-based position of the passage in the prompt, set by
+	// Render. Answers cite passages by this number.
+	Number   int
+	ID       string
+	Source   string
+	Text     string
+	Score    float64
+	Metadata map[string]string
+}
+
+// Data is what templates are executed with.
+type Data struct {
+	Question string
+	Passages []Passage
+}
+
+// Set is a collection of named prompt templates.
+type Set struct {
+	templates *template.Template
+}
+
+// Default returns the built-in templates.
+func Default() *Set {
+	t, err := parse(template.New(""), builtin, "templates/*.tmpl")
+	if err != nil {
+		panic(fmt.Sprintf("prompt: built-in templates: %v", err))
+	}
+	return &Set{templates: t}
+}
+
+// Load returns the built-in templates overridden and extended by every
+// *.tmpl file in dir. A template is named after its file without extension,
+// and files may also redefine the "passages" partial.
+func Load(dir string) (*Set, error) {
+	set := Default()
+	if dir == "" {
+		return set, nil
+	}
+
+	t, err := parse(set.templates, os.DirFS(dir), "*.tmpl")
+	if err != nil {
+		return nil, err
+	}
+	return &Set{templates: t}, nil
+}
+
+func parse(t *template.Template, fsys fs.FS, pattern string) (*template.Template, error) {
+	files, err := fs.Glob(fsys, pattern)
+	if err != nil {
+		return nil, fmt.Errorf("prompt: %w", err)
+	}
+
+	for _, file := range files {
+		text, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, fmt.Errorf("prompt: %w", err)
+		}
+		name := strings.TrimSuffix(path.Base(file), ".tmpl")
+		if _, err := t.New(name).Parse(string(text)); err != nil {
+			return nil, fmt.Errorf("prompt: %s: %w", file, err)
+		}
+	}
+	return t, nil
+}
+
+// Names returns the names of the available prompts.
+func (s *Set) Names() []string {
+	var names []string
+	for _, t := range s.templates.Templates() {
+		if t.Name() != "" && !partials[t.Name()] {
+			names = append(names, t.Name())
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Render executes the named template. Passages are numbered in the order given.
+func (s *Set) Render(name string, data Data) (string, error) {
+	t := s.templates.Lookup(name)
+	if t == nil || partials[name] {
+		return "", fmt.Errorf("prompt: unknown template %q, available: %v", name, s.Names())
+	}
+
+	data.Passages = numbered(data.Passages)
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("prompt: %w", err)
+	}
+	return buf.String(), nil
+}
+
+func numbered(passages []Passage) []Passage {
+	out := make([]Passage, len(passages))
+	for i, p := range passages {
+		p.Number = i + 1
+		out[i] = p
+	}
+	return out
+}
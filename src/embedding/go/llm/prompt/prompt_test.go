@@ -0,0 +1,113 @@
+package prompt
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeTemplates writes each template to a file of a temporary directory.
+func writeTemplates(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, text := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(text), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestRender(t *testing.T) {
+	data := Data{
+		Question: "Who is Charlotte?",
+		Passages: []Passage{
+			{Text: "Charlotte is a spider.", Source: "web.txt"},
+			{Text: "Wilbur is a pig."},
+		},
+	}
+
+	for _, name := range Default().Names() {
+		t.Run(name, func(t *testing.T) {
+			prompt, err := Default().Render(name, data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range []string{
+				"<question>\nWho is Charlotte?\n</question>",
+				"<context>\n[1] (source: web.txt) Charlotte is a spider.\n[2] Wilbur is a pig.\n</context>",
+			} {
+				if !strings.Contains(prompt, want) {
+					t.Errorf("prompt does not contain %q:\n%s", want, prompt)
+				}
+			}
+		})
+	}
+}
+
+func TestRenderErrors(t *testing.T) {
+	for _, name := range []string{"missing", "passages", "partials"} {
+		_, err := Default().Render(name, Data{})
+		if err == nil || !strings.HasPrefix(err.Error(), "prompt: unknown template "+`"`+name+`"`) {
+			t.Errorf("%s: got error %v, want an unknown template error", name, err)
+		}
+	}
+}
+
+func TestNames(t *testing.T) {
+	want := []string{CiteSources, StrictGrounded, Summarise}
+	if got := Default().Names(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := writeTemplates(t, map[string]string{
+		"strict-grounded.tmpl": "Answer {{.Question}}\n{{template \"passages\" .}}",
+		"bullets.tmpl":         "List {{.Question}}\n{{template \"passages\" .}}",
+		"partials.tmpl":        "{{define \"passages\"}}{{range .Passages}}- {{.Text}} ({{.Number}})\n{{end}}{{end}}",
+		"notes.txt":            "not a template",
+	})
+	set, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"bullets", CiteSources, StrictGrounded, Summarise}
+	if got := set.Names(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got names %v, want %v", got, want)
+	}
+
+	data := Data{Question: "animals", Passages: []Passage{{Text: "a spider"}, {Text: "a pig"}}}
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: StrictGrounded, want: "Answer animals\n- a spider (1)\n- a pig (2)\n"},
+		{name: "bullets", want: "List animals\n- a spider (1)\n- a pig (2)\n"},
+	}
+	for _, test := range tests {
+		got, err := set.Render(test.name, data)
+		if err != nil || got != test.want {
+			t.Errorf("%s: got %q, %v, want %q", test.name, got, err, test.want)
+		}
+	}
+
+	// The built-in templates are left as they were
+	if got, _ := Default().Render(StrictGrounded, data); !strings.Contains(got, "[1] a spider\n") {
+		t.Errorf("Load changed the built-in templates:\n%s", got)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	if set, err := Load(""); err != nil || !reflect.DeepEqual(set.Names(), Default().Names()) {
+		t.Errorf("got %v, %v, want the built-in templates for no directory", set, err)
+	}
+
+	dir := writeTemplates(t, map[string]string{"broken.tmpl": "{{.Question"})
+	if _, err := Load(dir); err == nil || !strings.HasPrefix(err.Error(), "prompt: broken.tmpl: ") {
+		t.Errorf("got error %v, want a parse error naming the file", err)
+	}
+}
@@ -0,0 +1,89 @@
+// Package provider builds an llm.Provider from configuration, so programs can
+// switch backends with a flag or environment variable.
+package provider
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	anthropicoption "github.com/anthropics/anthropic-sdk-go/option"
+	openaioption "github.com/openai/openai-go/option"
+
+	"github.com/jacygao/ai/llm"
+	"github.com/jacygao/ai/llm/anthropic"
+	"github.com/jacygao/ai/llm/ollama"
+	"github.com/jacygao/ai/llm/openai"
+)
+
+const (
+	Anthropic = "anthropic"
+	OpenAI    = "openai"
+	Ollama    = "ollama"
+)
+
+type Config struct {
+	// Name is one of Anthropic, OpenAI or Ollama.
+	Name       string
+	Model      string
+	EmbedModel string
+	// BaseURL overrides the API endpoint, e.g. a remote Ollama server.
+	BaseURL string
+	// APIKey overrides the provider's default API key environment variable.
+	APIKey string
+}
+
+// RegisterFlags binds the config to command line flags. Defaults come from the
+// LLM_PROVIDER, LLM_MODEL, LLM_EMBED_MODEL and LLM_BASE_URL environment
+// variables, falling back to defaultName for the provider.
+func (c *Config) RegisterFlags(fs *flag.FlagSet, defaultName string) {
+	fs.StringVar(&c.Name, "provider", getenv("LLM_PROVIDER", defaultName), "LLM provider: anthropic, openai or ollama")
+	fs.StringVar(&c.Model, "model", os.Getenv("LLM_MODEL"), "Chat model, provider default when empty")
+	fs.StringVar(&c.EmbedModel, "embed-model", os.Getenv("LLM_EMBED_MODEL"), "Embedding model, provider default when empty")
+	fs.StringVar(&c.BaseURL, "llm-url", os.Getenv("LLM_BASE_URL"), "LLM API base URL, provider default when empty")
+}
+
+// New creates the provider described by cfg.
+func New(cfg Config) (llm.Provider, error) {
+	switch cfg.Name {
+	case Anthropic:
+		var opts []anthropicoption.RequestOption
+		if cfg.BaseURL != "" {
+			opts = append(opts, anthropicoption.WithBaseURL(cfg.BaseURL))
+		}
+		if cfg.APIKey != "" {
+			opts = append(opts, anthropicoption.WithAPIKey(cfg.APIKey))
+		}
+		return anthropic.New(cfg.Model, opts...), nil
+	case OpenAI:
+		var opts []openaioption.RequestOption
+		if cfg.BaseURL != "" {
+			opts = append(opts, openaioption.WithBaseURL(cfg.BaseURL))
+		}
+		if cfg.APIKey != "" {
+			opts = append(opts, openaioption.WithAPIKey(cfg.APIKey))
+		}
+		return openai.New(cfg.Model, cfg.EmbedModel, opts...), nil
+	case Ollama:
+		client := ollama.NewClient()
+		if cfg.BaseURL != "" {
+			client.BaseURL = cfg.BaseURL
+		}
+		if cfg.Model != "" {
+			client.ChatModel = cfg.Model
+		}
+		if cfg.EmbedModel != "" {
+			client.EmbedModel = cfg.EmbedModel
+		}
+		return client, nil
+	default:
+		return nil, fmt.Errorf("unknown llm provider %q", cfg.Name)
+	}
+}
+
+func getenv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
@@ -0,0 +1,23 @@
+package llm
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+var scorePattern = regexp.MustCompile(`\d+(\.\d+)?`)
+
+// ParseScore reads the first number in a model's answer to a "rate from 0 to
+// max" prompt and returns it scaled to [0, 1]. Numbers above max count as max.
+func ParseScore(answer string, max float64) (float64, error) {
+	match := scorePattern.FindString(answer)
+	if match == "" {
+		return 0, fmt.Errorf("no score in answer %q", answer)
+	}
+	score, err := strconv.ParseFloat(match, 64)
+	if err != nil {
+		return 0, err
+	}
+	return min(score, max) / max, nil
+}
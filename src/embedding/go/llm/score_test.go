@@ -0,0 +1,29 @@
+package llm
+
+import "testing"
+
+func TestParseScore(t *testing.T) {
+	tests := []struct {
+		answer string
+		want   float64
+	}{
+		{answer: "7", want: 0.7},
+		{answer: "Score: 8.5/10", want: 0.85},
+		{answer: " 0\n", want: 0},
+		{answer: "12", want: 1},
+	}
+	for _, test := range tests {
+		got, err := ParseScore(test.answer, 10)
+		if err != nil {
+			t.Errorf("ParseScore(%q): %v", test.answer, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("ParseScore(%q) = %v, want %v", test.answer, got, test.want)
+		}
+	}
+
+	if _, err := ParseScore("very relevant", 10); err == nil {
+		t.Error("got no error for an answer without a number")
+	}
+}
@@ -0,0 +1,370 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jacygao/ai/bm25"
+	"github.com/jacygao/ai/llm"
+	"github.com/jacygao/ai/llm/cite"
+	"github.com/jacygao/ai/llm/groundedness"
+	"github.com/jacygao/ai/llm/ollama"
+	"github.com/jacygao/ai/llm/prompt"
+	"github.com/jacygao/ai/llm/provider"
+	"github.com/jacygao/ai/retriever"
+	"github.com/jacygao/ai/vector/redis"
+)
+
+// overFetch is how many more documents than k are retrieved for reranking.
+const overFetch = 3
+
+var corpus = []string{
+	"Jacy is a software engineer.",
+	"Charlotte will become a lawyer in a few weeks after her official admission.",
+	"Jacy married Charlotte 3 years ago.",
+	"Matt and Charlotte are best friends.",
+	"Matt and Charlotte grow up together and they have known each other since they were 3 years old.",
+	"Christiane is Charlotte's mum.",
+}
+
+// usage example:
+// go run ./rag -backend hybrid -fusion weighted -alpha 0.7
+// go run ./rag -backend bm25 -index docs.bm25
+func main() {
+	var cfg provider.Config
+	cfg.RegisterFlags(flag.CommandLine, provider.Ollama)
+	backend := flag.String("backend", "bm25", "Where to retrieve documents from: bm25, memory, redis, pgvector or hybrid")
+	top := flag.Int("k", 3, "Documents to answer from")
+	indexPath := flag.String("index", "", "BM25 index file made by go run ./bm25/cmd build, instead of the built-in example documents")
+	databaseURL := flag.String("database-url", os.Getenv("DATABASE_URL"), "Postgres connection string of the pgvector backend")
+	redisAddr := flag.String("redis-addr", redis.DefaultOptions().Addr, "Address of the redis-stack server of the redis backend")
+	filter := flag.String("filter", "", "Redis query restricting the redis backend to matching documents, like '@corpus:{example}'")
+	dim := flag.Int("dim", 384, "Embedding dimensions of the redis and pgvector indexes")
+	fusionName := flag.String("fusion", retriever.RRF.String(), "How the hybrid backend fuses the BM25 and vector rankings: rrf or weighted")
+	alpha := flag.Float64("alpha", 0.5, "Weight of the vector scores in weighted fusion, BM25 gets 1-alpha")
+	fetch := flag.Int("fetch", 20, "Documents the hybrid backend takes from each retriever before fusing")
+	rerank := flag.Bool("rerank", false, "Over-fetch documents and rerank them with Ollama before answering")
+	promptDir := flag.String("prompts", "", "Directory of *.tmpl prompt templates overriding the built-in ones")
+	templateName := flag.String("template", prompt.CiteSources, "Name of the prompt template to answer with")
+	contextTokens := flag.Int("context-tokens", 2048, "Token budget of the prompt, lowest ranked passages are dropped to fit")
+	judge := flag.Bool("judge", false, "Also ask the model to rate how well each answer is grounded in the documents")
+	flag.Parse()
+
+	prompts, err := prompt.Load(*promptDir)
+	if err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(1)
+	}
+
+	llmProvider, err := provider.New(cfg)
+	if err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(1)
+	}
+	reranker, isOllama := llmProvider.(*ollama.Client)
+	if *rerank && !isOllama {
+		fmt.Printf("Error: -rerank needs the %s provider\n", provider.Ollama)
+		os.Exit(1)
+	}
+
+	ctx := context.Background()
+	var r retriever.Retriever
+	switch *backend {
+	case "bm25":
+		r, err = newBM25(*indexPath)
+	case "memory":
+		r, err = newMemory(ctx, llmProvider)
+	case "redis":
+		r, err = newRedis(ctx, llmProvider, *redisAddr, *dim)
+	case "pgvector":
+		r, err = newPGVector(ctx, llmProvider, *databaseURL, *dim)
+	case "hybrid":
+		var fusion retriever.Fusion
+		fusion, err = retriever.ParseFusion(*fusionName)
+		if err == nil {
+			r, err = newHybrid(ctx, llmProvider, *indexPath, fusion, *alpha, *fetch)
+		}
+	default:
+		err = fmt.Errorf("unknown backend %q", *backend)
+	}
+	if err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(1)
+	}
+
+	session := ollama.NewSession(llmProvider)
+	session.Prompts = prompts
+	session.Template = *templateName
+	session.ContextTokens = *contextTokens
+
+	checker := groundedness.NewChecker(nil)
+	if *judge {
+		checker.Judge = llmProvider
+	}
+
+	reader := bufio.NewReader(os.Stdin)
+	for {
+		fmt.Print("Enter your question : ")
+		line, err := reader.ReadString('\n')
+		// A last question without a newline is still answered
+		if err != nil && (err != io.EOF || strings.TrimSpace(line) == "") {
+			if err != io.EOF {
+				fmt.Println("Error:", err)
+			}
+			fmt.Println("\nExiting...")
+			break
+		}
+		originalQuery := strings.TrimSpace(line)
+		if originalQuery == "exit" {
+			fmt.Println("Exiting...")
+			break
+		}
+
+		// Follow-up questions are made standalone before searching
+		searchQuery, err := session.Rewrite(ctx, originalQuery)
+		if err != nil {
+			fmt.Println("Error:", err)
+			searchQuery = originalQuery
+		}
+		if searchQuery != originalQuery {
+			fmt.Printf("Searching for: %s\n", searchQuery)
+		}
+
+		opts := retriever.Options{K: *top, Filter: *filter}
+		if *rerank {
+			opts.K = *top * overFetch
+		}
+		docs, err := r.Retrieve(ctx, searchQuery, opts)
+		if err != nil {
+			fmt.Println("Error:", err)
+			continue
+		}
+
+		found := []prompt.Passage{}
+		for _, doc := range docs {
+			fmt.Printf("Score for Document %s: %.4f%s\n", doc.ID, doc.Score, formatSignals(doc.Signals))
+			source := *backend + " document " + doc.ID
+			if title := doc.Metadata["title"]; title != "" {
+				source = title
+			}
+			found = append(found, prompt.Passage{
+				ID:       doc.ID,
+				Source:   source,
+				Text:     doc.Text,
+				Score:    doc.Score,
+				Metadata: doc.Metadata,
+			})
+		}
+		if *rerank {
+			found = rerankDocs(ctx, reranker, searchQuery, found, *top)
+		}
+
+		answer, err := session.Ask(ctx, originalQuery, found, printText)
+		if err != nil {
+			fmt.Println("Error:", err)
+		} else {
+			printCitations(answer)
+			printGroundedness(ctx, checker, originalQuery, answer)
+		}
+		fmt.Println("")
+	}
+}
+
+// newBM25 loads the index file at path, or indexes the example documents
+// when there is none.
+func newBM25(path string) (*retriever.BM25, error) {
+	if path != "" {
+		index, err := bm25.Load(path)
+		if err != nil {
+			return nil, err
+		}
+		return retriever.NewBM25(index), nil
+	}
+
+	// Stemmed like the bm25 build command does by default, so that
+	// "Charlotte's" and "Charlotte" are the same term
+	index := bm25.NewIndex()
+	index.Analyzer = bm25.EnglishAnalyzer(bm25.EnglishStopwords())
+	for i, doc := range corpus {
+		if err := index.Add(strconv.Itoa(i+1), doc); err != nil {
+			return nil, err
+		}
+	}
+	return retriever.NewBM25(index), nil
+}
+
+// newMemory embeds the example documents into an in-memory vector index.
+func newMemory(ctx context.Context, embedder llm.Embedder) (*retriever.Vector, error) {
+	return newVector(ctx, embedder, corpusIDs(), corpus)
+}
+
+func newVector(ctx context.Context, embedder llm.Embedder, ids []string, texts []string) (*retriever.Vector, error) {
+	r := retriever.NewVector(embedder)
+	if err := r.Add(ctx, ids, texts); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// newRedis stores the example documents in redis-stack, tagged with the
+// corpus they come from.
+func newRedis(ctx context.Context, embedder llm.Embedder, addr string, dim int) (*retriever.Redis, error) {
+	opts := redis.DefaultOptions()
+	opts.Addr = addr
+	opts.Dim = dim
+	opts.Fields = []redis.Field{{Name: "corpus", Type: redis.TagField}}
+	client, err := redis.NewRedisClient(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	r := retriever.NewRedis(embedder, client)
+	docs := make([]retriever.Document, len(corpus))
+	for i, id := range corpusIDs() {
+		docs[i] = retriever.Document{ID: id, Text: corpus[i], Metadata: map[string]string{"corpus": "example"}}
+	}
+	if err := r.AddDocuments(ctx, docs); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// newPGVector inserts the example documents into Postgres, unless the table
+// already has documents from an earlier run.
+func newPGVector(ctx context.Context, embedder llm.Embedder, databaseURL string, dim int) (*retriever.PGVector, error) {
+	pool, err := pgxpool.New(ctx, databaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to Postgres: %w", err)
+	}
+	r := retriever.NewPGVector(embedder, pool)
+	if err := r.Init(ctx, dim); err != nil {
+		return nil, err
+	}
+	n, err := r.Len(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		if _, err := r.Add(ctx, corpus); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// newHybrid fuses BM25 with an in-memory vector index of the same
+// documents, so it runs without Redis or Postgres. The documents of an index
+// file are all embedded at start.
+func newHybrid(ctx context.Context, embedder llm.Embedder, indexPath string, fusion retriever.Fusion, alpha float64, fetch int) (*retriever.Hybrid, error) {
+	lexical, err := newBM25(indexPath)
+	if err != nil {
+		return nil, err
+	}
+	var ids, texts []string
+	for _, doc := range lexical.Index.Documents() {
+		if doc.Text != "" {
+			ids = append(ids, doc.ID)
+			texts = append(texts, doc.Text)
+		}
+	}
+	vector, err := newVector(ctx, embedder, ids, texts)
+	if err != nil {
+		return nil, err
+	}
+	h := retriever.NewHybrid(lexical, vector)
+	h.Fusion = fusion
+	h.Alpha = alpha
+	h.Fetch = fetch
+	return h, nil
+}
+
+func corpusIDs() []string {
+	ids := make([]string, len(corpus))
+	for i := range corpus {
+		ids[i] = strconv.Itoa(i + 1)
+	}
+	return ids
+}
+
+// formatSignals shows how each retriever ranked a fused document.
+func formatSignals(signals map[string]retriever.Signal) string {
+	if len(signals) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	for _, name := range []string{retriever.LexicalSignal, retriever.VectorSignal} {
+		if signal, ok := signals[name]; ok {
+			fmt.Fprintf(&b, " (%s #%d %.4f)", name, signal.Rank, signal.Score)
+		} else {
+			fmt.Fprintf(&b, " (%s -)", name)
+		}
+	}
+	return b.String()
+}
+
+// rerankDocs reorders the over-fetched passages by their relevance to the
+// query and keeps the best k. The retrieval order is kept if reranking fails.
+func rerankDocs(ctx context.Context, reranker *ollama.Client, query string, passages []prompt.Passage, k int) []prompt.Passage {
+	k = min(k, len(passages))
+	texts := make([]string, len(passages))
+	for i, p := range passages {
+		texts[i] = p.Text
+	}
+
+	ranked, err := reranker.Rerank(ctx, query, texts)
+	if err != nil {
+		fmt.Println("Error reranking:", err)
+		return passages[:k]
+	}
+
+	reranked := make([]prompt.Passage, 0, k)
+	for _, r := range ranked[:k] {
+		fmt.Printf("Rerank Score for %q: %.2f\n", r.Text, r.Score)
+		p := passages[r.Index]
+		p.Score = r.Score
+		reranked = append(reranked, p)
+	}
+	return reranked
+}
+
+// printCitations lists the sources the answer cited and warns about
+// sentences that no retrieved document supports.
+func printCitations(answer *cite.Answer) {
+	for _, source := range answer.Sources() {
+		fmt.Printf("\n[%d] %s", source.Number, source.Source)
+	}
+	if invalid := answer.InvalidCitations(); len(invalid) > 0 {
+		fmt.Printf("\nWarning: cited passages %v were not retrieved", invalid)
+	}
+	for _, sentence := range answer.Unsupported() {
+		fmt.Printf("\nUnsupported: %q", sentence.Text)
+	}
+}
+
+// printGroundedness reports whether the answer is grounded in the passages
+// its prompt contained, rather than every retrieved document, and logs
+// answers that look made up.
+func printGroundedness(ctx context.Context, checker *groundedness.Checker, query string, answer *cite.Answer) {
+	result, err := checker.Check(ctx, query, answer.Text, answer.Passages)
+	if err != nil {
+		fmt.Println("\nError checking groundedness:", err)
+		return
+	}
+	fmt.Printf("\nVerdict: %s (confidence %.2f)", result.Verdict, result.Confidence)
+	if result.Verdict == groundedness.HallucinationSuspect {
+		log.Printf("possible hallucination for %q: overlap %.2f, unsupported %q", query, result.Overlap, result.Unsupported)
+	}
+}
+
+// printText prints the answer as it streams in.
+func printText(text string) error {
+	fmt.Print(text)
+	return nil
+}
@@ -0,0 +1,29 @@
+package retriever
+
+import (
+	"context"
+
+	"github.com/jacygao/ai/bm25"
+)
+
+// BM25 retrieves documents from a full text index. The query can use the
+// bm25 query syntax. Metadata holds the document's fields.
+type BM25 struct {
+	Index *bm25.Index
+}
+
+func NewBM25(index *bm25.Index) *BM25 {
+	return &BM25{Index: index}
+}
+
+func (r *BM25) Retrieve(ctx context.Context, query string, opts Options) ([]Document, error) {
+	if opts.Filter != "" {
+		return nil, ErrFilterUnsupported
+	}
+	results := r.Index.Search(query, opts.k())
+	docs := make([]Document, len(results))
+	for i, result := range results {
+		docs[i] = Document{ID: result.ID, Text: result.Text, Metadata: result.Fields, Score: result.Score}
+	}
+	return docs, nil
+}
@@ -0,0 +1,179 @@
+package retriever
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+)
+
+// Fusion selects how Hybrid combines the rankings of its retrievers.
+type Fusion int
+
+const (
+	// RRF is reciprocal rank fusion: a document scores the sum of
+	// 1/(RRFK + rank) over the rankings it is in, so only ranks count
+	// (Cormack et al., "Reciprocal Rank Fusion outperforms Condorcet and
+	// individual Rank Learning Methods", 2009).
+	RRF Fusion = iota
+	// Weighted min-max normalises each retriever's scores to [0, 1] and
+	// sums them weighted by Alpha. A document missing from a ranking scores
+	// 0 in it.
+	Weighted
+)
+
+var fusionNames = map[Fusion]string{
+	RRF:      "rrf",
+	Weighted: "weighted",
+}
+
+func (f Fusion) String() string {
+	if name, ok := fusionNames[f]; ok {
+		return name
+	}
+	return fmt.Sprintf("Fusion(%d)", int(f))
+}
+
+// ParseFusion returns the fusion named rrf or weighted.
+func ParseFusion(name string) (Fusion, error) {
+	for fusion, fusionName := range fusionNames {
+		if name == fusionName {
+			return fusion, nil
+		}
+	}
+	return 0, fmt.Errorf("retriever: unknown fusion %q", name)
+}
+
+// Names of the signals in Document.Signals of hybrid results.
+const (
+	LexicalSignal = "bm25"
+	VectorSignal  = "vector"
+)
+
+// DefaultRRFK is the rank constant of the RRF paper.
+const DefaultRRFK = 60
+
+// Hybrid runs a full text and a vector retriever concurrently and fuses
+// their rankings, so documents matching the query's words and documents
+// matching its meaning both make it to the top.
+type Hybrid struct {
+	Lexical Retriever
+	Vector  Retriever
+	Fusion  Fusion
+	// Alpha is the weight of the vector scores in Weighted fusion, the
+	// lexical ones get 1-Alpha.
+	Alpha float64
+	// RRFK dampens the difference between top ranks in RRF fusion.
+	RRFK float64
+	// Fetch is how many documents to take from each retriever, at least K.
+	// Fusing deeper rankings lets documents ranked fairly well by both
+	// overtake ones ranked first by only one.
+	Fetch int
+}
+
+func NewHybrid(lexical Retriever, vector Retriever) *Hybrid {
+	return &Hybrid{
+		Lexical: lexical,
+		Vector:  vector,
+		Fusion:  RRF,
+		Alpha:   0.5,
+		RRFK:    DefaultRRFK,
+		Fetch:   20,
+	}
+}
+
+// Retrieve returns the best documents by fused score, each once, with the
+// rank and score every retriever gave it in Signals.
+func (h *Hybrid) Retrieve(ctx context.Context, query string, opts Options) ([]Document, error) {
+	k := opts.k()
+	fetch := opts
+	fetch.K = max(h.Fetch, k)
+
+	names := []string{LexicalSignal, VectorSignal}
+	retrievers := []Retriever{h.Lexical, h.Vector}
+	rankings := make([][]Document, len(retrievers))
+	errs := make([]error, len(retrievers))
+	var wg sync.WaitGroup
+	for i, r := range retrievers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rankings[i], errs[i] = r.Retrieve(ctx, query, fetch)
+		}()
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("error retrieving from %s: %w", names[i], err)
+		}
+	}
+
+	byID := make(map[string]*Document)
+	var fused []*Document
+	for i, ranking := range rankings {
+		weight := 1.0
+		if h.Fusion == Weighted {
+			weight = 1 - h.Alpha
+			if names[i] == VectorSignal {
+				weight = h.Alpha
+			}
+		}
+		normalized := normalize(ranking)
+
+		for rank, doc := range ranking {
+			d, exists := byID[doc.ID]
+			if !exists {
+				d = &Document{ID: doc.ID, Text: doc.Text, Metadata: doc.Metadata, Signals: make(map[string]Signal)}
+				byID[doc.ID] = d
+				fused = append(fused, d)
+			}
+			if _, seen := d.Signals[names[i]]; seen {
+				// A retriever returning a document twice only counts its best rank
+				continue
+			}
+			if d.Metadata == nil {
+				d.Metadata = doc.Metadata
+			}
+			d.Signals[names[i]] = Signal{Rank: rank + 1, Score: doc.Score}
+
+			switch h.Fusion {
+			case Weighted:
+				d.Score += weight * normalized[rank]
+			default:
+				d.Score += 1 / (h.RRFK + float64(rank+1))
+			}
+		}
+	}
+
+	sort.Slice(fused, func(a int, b int) bool {
+		if fused[a].Score != fused[b].Score {
+			return fused[a].Score > fused[b].Score
+		}
+		return fused[a].ID < fused[b].ID
+	})
+	docs := make([]Document, min(k, len(fused)))
+	for i := range docs {
+		docs[i] = *fused[i]
+	}
+	return docs, nil
+}
+
+// normalize min-max scales the scores of a ranking to [0, 1]. Equal scores
+// all become 1.
+func normalize(ranking []Document) []float64 {
+	lowest, highest := math.Inf(1), math.Inf(-1)
+	for _, doc := range ranking {
+		lowest = min(lowest, doc.Score)
+		highest = max(highest, doc.Score)
+	}
+	normalized := make([]float64, len(ranking))
+	for i, doc := range ranking {
+		if highest > lowest {
+			normalized[i] = (doc.Score - lowest) / (highest - lowest)
+		} else {
+			normalized[i] = 1
+		}
+	}
+	return normalized
+}
@@ -0,0 +1,194 @@
+package retriever
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// ranking is a retriever returning fixed documents, best first, and
+// recording the options it was called with.
+type ranking struct {
+	docs []Document
+	err  error
+
+	mu   sync.Mutex
+	opts []Options
+}
+
+func (r *ranking) Retrieve(ctx context.Context, query string, opts Options) ([]Document, error) {
+	r.mu.Lock()
+	r.opts = append(r.opts, opts)
+	r.mu.Unlock()
+	if r.err != nil {
+		return nil, r.err
+	}
+	return r.docs[:min(opts.k(), len(r.docs))], nil
+}
+
+func docs(idScores ...any) []Document {
+	var out []Document
+	for i := 0; i < len(idScores); i += 2 {
+		out = append(out, Document{ID: idScores[i].(string), Text: "text of " + idScores[i].(string), Score: idScores[i+1].(float64)})
+	}
+	return out
+}
+
+func TestHybridFusion(t *testing.T) {
+	lexical := docs("a", 10.0, "b", 5.0, "c", 0.0)
+	vector := docs("c", 0.9, "a", 0.5, "d", 0.1)
+
+	tests := []struct {
+		name   string
+		fusion Fusion
+		alpha  float64
+		want   []string
+		scores []float64
+	}{
+		{
+			name:   "rrf",
+			fusion: RRF,
+			want:   []string{"a", "c", "b", "d"},
+			scores: []float64{1.0/61 + 1.0/62, 1.0/63 + 1.0/61, 1.0 / 62, 1.0 / 63},
+		},
+		{
+			name:   "weighted",
+			fusion: Weighted,
+			alpha:  0.5,
+			want:   []string{"a", "c", "b", "d"},
+			scores: []float64{0.5*1 + 0.5*0.5, 0.5*0 + 0.5*1, 0.5 * 0.5, 0.5 * 0},
+		},
+		{
+			name:   "weighted towards vectors",
+			fusion: Weighted,
+			alpha:  0.9,
+			want:   []string{"c", "a", "b", "d"},
+			scores: []float64{0.9, 0.1 + 0.9*0.5, 0.1 * 0.5, 0},
+		},
+		{
+			name:   "vectors only",
+			fusion: Weighted,
+			alpha:  1,
+			// Ties are broken by ID
+			want:   []string{"c", "a", "b", "d"},
+			scores: []float64{1, 0.5, 0, 0},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h := NewHybrid(&ranking{docs: lexical}, &ranking{docs: vector})
+			h.Fusion = test.fusion
+			h.Alpha = test.alpha
+
+			results, err := h.Retrieve(context.Background(), "query", Options{K: 10})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(results) != len(test.want) {
+				t.Fatalf("got %d documents, want %d", len(results), len(test.want))
+			}
+			for i, doc := range results {
+				if doc.ID != test.want[i] || math.Abs(doc.Score-test.scores[i]) > 1e-12 {
+					t.Errorf("rank %d: got %s scoring %v, want %s scoring %v", i+1, doc.ID, doc.Score, test.want[i], test.scores[i])
+				}
+			}
+		})
+	}
+}
+
+func TestHybridSignals(t *testing.T) {
+	lexical := docs("a", 10.0, "b", 5.0, "a", 4.0)
+	lexical[1].Metadata = map[string]string{"source": "lexical"}
+	vector := docs("b", 0.9, "c", 0.5)
+	vector[0].Metadata = map[string]string{"source": "vector"}
+	vector[1].Metadata = map[string]string{"source": "vector"}
+
+	h := NewHybrid(&ranking{docs: lexical}, &ranking{docs: vector})
+	results, err := h.Retrieve(context.Background(), "query", Options{K: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]struct {
+		signals map[string]Signal
+		source  string
+	}{
+		// A document returned twice by a retriever keeps its best rank
+		"a": {signals: map[string]Signal{LexicalSignal: {Rank: 1, Score: 10}}},
+		"b": {signals: map[string]Signal{LexicalSignal: {Rank: 2, Score: 5}, VectorSignal: {Rank: 1, Score: 0.9}}, source: "lexical"},
+		"c": {signals: map[string]Signal{VectorSignal: {Rank: 2, Score: 0.5}}, source: "vector"},
+	}
+	if len(results) != len(want) {
+		t.Fatalf("got %d documents, want each of %d once", len(results), len(want))
+	}
+	for _, doc := range results {
+		w := want[doc.ID]
+		if len(doc.Signals) != len(w.signals) {
+			t.Errorf("%s: got signals %v, want %v", doc.ID, doc.Signals, w.signals)
+		}
+		for name, signal := range w.signals {
+			if doc.Signals[name] != signal {
+				t.Errorf("%s: got %s signal %+v, want %+v", doc.ID, name, doc.Signals[name], signal)
+			}
+		}
+		if doc.Metadata["source"] != w.source || doc.Text != "text of "+doc.ID {
+			t.Errorf("%s: got text %q and metadata %v", doc.ID, doc.Text, doc.Metadata)
+		}
+	}
+}
+
+func TestHybridFetch(t *testing.T) {
+	lexical := &ranking{docs: docs("a", 3.0, "b", 2.0, "c", 1.0)}
+	vector := &ranking{docs: docs("d", 3.0, "e", 2.0)}
+	h := NewHybrid(lexical, vector)
+	h.Fetch = 4
+
+	for _, test := range []struct {
+		k     int
+		fetch int
+		want  int
+	}{
+		{k: 2, fetch: 4, want: 2},
+		{k: 10, fetch: 10, want: 5},
+		{k: 0, fetch: DefaultK, want: 5},
+	} {
+		results, err := h.Retrieve(context.Background(), "query", Options{K: test.k, Filter: "@corpus:{web}"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(results) != test.want {
+			t.Errorf("k=%d: got %d documents, want %d", test.k, len(results), test.want)
+		}
+		for _, r := range []*ranking{lexical, vector} {
+			opts := r.opts[len(r.opts)-1]
+			if opts.K != test.fetch || opts.Filter != "@corpus:{web}" {
+				t.Errorf("k=%d: got retriever options %+v, want K %d and the filter", test.k, opts, test.fetch)
+			}
+		}
+	}
+}
+
+func TestHybridErrors(t *testing.T) {
+	h := NewHybrid(&ranking{docs: docs("a", 1.0)}, &ranking{err: errors.New("connection refused")})
+	results, err := h.Retrieve(context.Background(), "query", Options{})
+	if err == nil || err.Error() != "error retrieving from vector: connection refused" || results != nil {
+		t.Errorf("got %v, %v, want the vector retriever's error", results, err)
+	}
+}
+
+func TestParseFusion(t *testing.T) {
+	for _, fusion := range []Fusion{RRF, Weighted} {
+		if got, err := ParseFusion(fusion.String()); err != nil || got != fusion {
+			t.Errorf("ParseFusion(%q): got %v, %v", fusion.String(), got, err)
+		}
+	}
+	if _, err := ParseFusion("borda"); err == nil || !strings.Contains(err.Error(), `"borda"`) {
+		t.Errorf("got error %v, want an unknown fusion error", err)
+	}
+	if got := Fusion(7).String(); got != "Fusion(7)" {
+		t.Errorf("got %q, want Fusion(7)", got)
+	}
+}
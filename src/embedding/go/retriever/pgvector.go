@@ -0,0 +1,123 @@
+package retriever
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jacygao/ai/llm"
+)
+
+// PGVector retrieves the documents nearest to the query from a Postgres
+// table with a pgvector embedding column, by L2 distance. Score is the
+// negated distance.
+type PGVector struct {
+	Embedder llm.Embedder
+	Pool     *pgxpool.Pool
+	// Table has an integer id, a text content and a vector embedding column.
+	Table string
+}
+
+func NewPGVector(embedder llm.Embedder, pool *pgxpool.Pool) *PGVector {
+	return &PGVector{Embedder: embedder, Pool: pool, Table: "documents"}
+}
+
+// Init enables pgvector and creates the table and its index for embeddings
+// of dim dimensions, if they do not exist yet.
+func (r *PGVector) Init(ctx context.Context, dim int) error {
+	statements := []string{
+		"CREATE EXTENSION IF NOT EXISTS vector",
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+			id SERIAL PRIMARY KEY,
+			content TEXT,
+			embedding vector(%d)
+		)`, r.Table, dim),
+		fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %s_embedding_idx
+			ON %s USING ivfflat (embedding vector_l2_ops) WITH (lists = 100)`, r.Table, r.Table),
+	}
+	for _, statement := range statements {
+		if _, err := r.Pool.Exec(ctx, statement); err != nil {
+			return fmt.Errorf("error initialising %s: %w", r.Table, err)
+		}
+	}
+	return nil
+}
+
+// Len returns the number of documents in the table.
+func (r *PGVector) Len(ctx context.Context) (int, error) {
+	var n int
+	if err := r.Pool.QueryRow(ctx, fmt.Sprintf("SELECT count(*) FROM %s", r.Table)).Scan(&n); err != nil {
+		return 0, fmt.Errorf("error counting documents: %w", err)
+	}
+	return n, nil
+}
+
+// Add embeds the texts in one batch and inserts them. The table assigns
+// the IDs, which are returned in text order.
+func (r *PGVector) Add(ctx context.Context, texts []string) ([]string, error) {
+	vectors, err := r.Embedder.Embed(ctx, texts)
+	if err != nil {
+		return nil, fmt.Errorf("error embedding documents: %w", err)
+	}
+	ids := make([]string, len(texts))
+	for i, text := range texts {
+		var id int64
+		err := r.Pool.QueryRow(ctx,
+			fmt.Sprintf("INSERT INTO %s (content, embedding) VALUES ($1, $2::vector) RETURNING id", r.Table),
+			text, vectorLiteral(vectors[i]),
+		).Scan(&id)
+		if err != nil {
+			return nil, fmt.Errorf("error inserting document: %w", err)
+		}
+		ids[i] = strconv.FormatInt(id, 10)
+	}
+	return ids, nil
+}
+
+func (r *PGVector) Retrieve(ctx context.Context, query string, opts Options) ([]Document, error) {
+	if opts.Filter != "" {
+		return nil, ErrFilterUnsupported
+	}
+	vectors, err := r.Embedder.Embed(ctx, []string{query})
+	if err != nil {
+		return nil, fmt.Errorf("error embedding query: %w", err)
+	}
+
+	rows, err := r.Pool.Query(ctx,
+		fmt.Sprintf(`SELECT id, content, embedding <-> $1::vector AS distance
+			FROM %s
+			ORDER BY distance
+			LIMIT $2`, r.Table),
+		vectorLiteral(vectors[0]), opts.k(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error querying documents: %w", err)
+	}
+	defer rows.Close()
+
+	var docs []Document
+	for rows.Next() {
+		var id int64
+		var content string
+		var distance float64
+		if err := rows.Scan(&id, &content, &distance); err != nil {
+			return nil, fmt.Errorf("error scanning document: %w", err)
+		}
+		docs = append(docs, Document{ID: strconv.FormatInt(id, 10), Text: content, Score: -distance})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error reading documents: %w", err)
+	}
+	return docs, nil
+}
+
+// vectorLiteral formats a vector in pgvector's input syntax, e.g. [0.1,0.2].
+func vectorLiteral(vector []float64) string {
+	values := make([]string, len(vector))
+	for i, v := range vector {
+		values[i] = strconv.FormatFloat(v, 'f', -1, 32)
+	}
+	return "[" + strings.Join(values, ",") + "]"
+}
@@ -0,0 +1,88 @@
+package retriever
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jacygao/ai/llm"
+	"github.com/jacygao/ai/vector/redis"
+)
+
+// Redis retrieves the documents nearest to the query from a redis-stack
+// vector index. Score depends on the metric of the index: the cosine
+// similarity for COSINE, the inner product for IP, both 1 - vector_distance,
+// and the negated distance for L2.
+type Redis struct {
+	Embedder llm.Embedder
+	Client   *redis.RedisClient
+}
+
+func NewRedis(embedder llm.Embedder, client *redis.RedisClient) *Redis {
+	return &Redis{Embedder: embedder, Client: client}
+}
+
+// Add embeds the texts in one batch and stores them under ids.
+func (r *Redis) Add(ctx context.Context, ids []string, texts []string) error {
+	if len(ids) != len(texts) {
+		return fmt.Errorf("got %d ids for %d texts", len(ids), len(texts))
+	}
+	docs := make([]Document, len(ids))
+	for i, id := range ids {
+		docs[i] = Document{ID: id, Text: texts[i]}
+	}
+	return r.AddDocuments(ctx, docs)
+}
+
+// AddDocuments embeds the documents in one batch and stores them with their
+// metadata. Metadata fields must be declared in the index to be filtered on.
+func (r *Redis) AddDocuments(ctx context.Context, docs []Document) error {
+	texts := make([]string, len(docs))
+	for i, doc := range docs {
+		texts[i] = doc.Text
+	}
+	vectors, err := r.Embedder.Embed(ctx, texts)
+	if err != nil {
+		return fmt.Errorf("error embedding documents: %w", err)
+	}
+	for i, doc := range docs {
+		if err := r.Client.Set(ctx, doc.ID, doc.Text, toFloat32(vectors[i]), doc.Metadata); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Retrieve applies opts.Filter as a Redis query, see redis.TagFilter and
+// redis.RangeFilter.
+func (r *Redis) Retrieve(ctx context.Context, query string, opts Options) ([]Document, error) {
+	vectors, err := r.Embedder.Embed(ctx, []string{query})
+	if err != nil {
+		return nil, fmt.Errorf("error embedding query: %w", err)
+	}
+	results, err := r.Client.SearchVector(ctx, toFloat32(vectors[0]), opts.k(), opts.Filter)
+	if err != nil {
+		return nil, err
+	}
+	docs := make([]Document, len(results))
+	for i, result := range results {
+		score, err := redisScore(r.Client.DistanceMetric(), result.Distance)
+		if err != nil {
+			return nil, err
+		}
+		docs[i] = Document{ID: result.Key, Text: result.Content, Metadata: result.Metadata, Score: score}
+	}
+	return docs, nil
+}
+
+// redisScore converts a vector_distance into a score, higher is nearer.
+// Redis reports COSINE and IP distances as 1 - similarity.
+func redisScore(metric string, distance float64) (float64, error) {
+	switch metric {
+	case "COSINE", "IP":
+		return 1 - distance, nil
+	case "L2":
+		return -distance, nil
+	default:
+		return 0, fmt.Errorf("unknown redis distance metric %q", metric)
+	}
+}
@@ -0,0 +1,37 @@
+package retriever
+
+import "testing"
+
+func TestRedisScore(t *testing.T) {
+	tests := []struct {
+		metric   string
+		distance float64
+		want     float64
+	}{
+		{metric: "COSINE", distance: 0.25, want: 0.75},
+		{metric: "IP", distance: -2, want: 3},
+		{metric: "L2", distance: 4, want: -4},
+	}
+	for _, test := range tests {
+		got, err := redisScore(test.metric, test.distance)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != test.want {
+			t.Errorf("%s distance %v: got score %v, want %v", test.metric, test.distance, got, test.want)
+		}
+	}
+
+	// Nearer documents must score higher in every metric
+	for _, metric := range []string{"COSINE", "IP", "L2"} {
+		near, _ := redisScore(metric, 0.1)
+		far, _ := redisScore(metric, 0.9)
+		if near <= far {
+			t.Errorf("%s: got score %v for the nearer document, want more than %v", metric, near, far)
+		}
+	}
+
+	if _, err := redisScore("HAMMING", 1); err == nil {
+		t.Error("got no error for an unknown metric")
+	}
+}
@@ -0,0 +1,55 @@
+// Package retriever finds the documents a RAG chat bot answers from, behind
+// one interface for full text search, vector search and both fused.
+package retriever
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrFilterUnsupported is returned by retrievers that cannot filter, rather
+// than returning documents the filter would have excluded.
+var ErrFilterUnsupported = errors.New("retriever: filters are not supported")
+
+// DefaultK is the number of documents retrieved when Options.K is zero.
+const DefaultK = 5
+
+// Retriever returns the documents most relevant to a query, best first.
+type Retriever interface {
+	Retrieve(ctx context.Context, query string, opts Options) ([]Document, error)
+}
+
+// Options tune a retrieval.
+type Options struct {
+	// K is the number of documents to return, DefaultK if zero.
+	K int
+	// Filter restricts retrieval to documents matching it, in the query
+	// syntax of the backend. Only Redis supports filters.
+	Filter string
+}
+
+func (o Options) k() int {
+	if o.K <= 0 {
+		return DefaultK
+	}
+	return o.K
+}
+
+// Document is a retrieved document. Score is higher for more relevant
+// documents but only comparable between documents from the same retriever.
+type Document struct {
+	ID       string            `json:"id"`
+	Text     string            `json:"text"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+	Score    float64           `json:"score"`
+	// Signals holds the rank and score each fused retriever gave the
+	// document, keyed by retriever name. It is only set by Hybrid.
+	Signals map[string]Signal `json:"signals,omitempty"`
+}
+
+// Signal is how one retriever ranked a document.
+type Signal struct {
+	// Rank starts at 1.
+	Rank  int     `json:"rank"`
+	Score float64 `json:"score"`
+}
@@ -0,0 +1,64 @@
+package retriever
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jacygao/ai/llm"
+	"github.com/jacygao/ai/vector/memory"
+)
+
+// Vector retrieves the documents whose embeddings are nearest to the
+// query's from an in-memory index. Score is the cosine similarity.
+type Vector struct {
+	Embedder llm.Embedder
+	Index    *memory.Index
+}
+
+func NewVector(embedder llm.Embedder) *Vector {
+	return &Vector{Embedder: embedder, Index: memory.NewIndex()}
+}
+
+// Add embeds the texts in one batch and indexes them under ids.
+func (r *Vector) Add(ctx context.Context, ids []string, texts []string) error {
+	if len(ids) != len(texts) {
+		return fmt.Errorf("got %d ids for %d texts", len(ids), len(texts))
+	}
+	vectors, err := r.Embedder.Embed(ctx, texts)
+	if err != nil {
+		return fmt.Errorf("error embedding documents: %w", err)
+	}
+	for i, id := range ids {
+		if err := r.Index.Add(id, texts[i], toFloat32(vectors[i])); err != nil {
+			return fmt.Errorf("error indexing document %q: %w", id, err)
+		}
+	}
+	return nil
+}
+
+func (r *Vector) Retrieve(ctx context.Context, query string, opts Options) ([]Document, error) {
+	if opts.Filter != "" {
+		return nil, ErrFilterUnsupported
+	}
+	vectors, err := r.Embedder.Embed(ctx, []string{query})
+	if err != nil {
+		return nil, fmt.Errorf("error embedding query: %w", err)
+	}
+	results, err := r.Index.Search(toFloat32(vectors[0]), opts.k())
+	if err != nil {
+		return nil, err
+	}
+	docs := make([]Document, len(results))
+	for i, result := range results {
+		docs[i] = Document{ID: result.ID, Text: result.Text, Score: result.Score}
+	}
+	return docs, nil
+}
+
+func toFloat32(input []float64) []float32 {
+	output := make([]float32, len(input))
+	for i, v := range input {
+		output[i] = float32(v)
+	}
+	return output
+}
@@ -0,0 +1,439 @@
+// Package hnsw is an in-memory approximate nearest neighbour index over a
+// hierarchical navigable small world graph (Malkov and Yashunin, "Efficient
+// and robust approximate nearest neighbor search using Hierarchical
+// Navigable Small World graphs", 2016). It needs no server, so it can back
+// tests and small deployments.
+package hnsw
+
+import (
+	"container/heap"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"sort"
+	"sync"
+
+	"github.com/jacygao/ai/vector/memory"
+)
+
+// Default graph parameters
+const (
+	DefaultM              = 16  // Links per node and layer
+	DefaultEfConstruction = 200 // Candidates considered when linking a new node
+	DefaultEfSearch       = 50  // Candidates considered when searching
+)
+
+var (
+	ErrNotFound  = errors.New("hnsw: vector not found")
+	ErrDimension = errors.New("hnsw: vector dimension mismatch")
+)
+
+// Metric is how the distance between two vectors is measured. Smaller
+// distances are nearer.
+type Metric int
+
+const (
+	// Cosine is 1 minus the cosine similarity.
+	Cosine Metric = iota
+	// L2 is the Euclidean distance.
+	L2
+	// Dot is the negated dot product, for vectors whose length matters.
+	Dot
+)
+
+var metricNames = map[Metric]string{
+	Cosine: "cosine",
+	L2:     "l2",
+	Dot:    "dot",
+}
+
+func (m Metric) String() string {
+	if name, ok := metricNames[m]; ok {
+		return name
+	}
+	return fmt.Sprintf("Metric(%d)", int(m))
+}
+
+// ParseMetric returns the metric named cosine, l2 or dot.
+func ParseMetric(name string) (Metric, error) {
+	for metric, metricName := range metricNames {
+		if name == metricName {
+			return metric, nil
+		}
+	}
+	return 0, fmt.Errorf("hnsw: unknown metric %q", name)
+}
+
+// Distance measures the distance between two vectors of the same length.
+func (m Metric) Distance(a, b []float32) float64 {
+	switch m {
+	case L2:
+		var sum float64
+		for i := range a {
+			d := float64(a[i] - b[i])
+			sum += d * d
+		}
+		return math.Sqrt(sum)
+	case Dot:
+		return -memory.Dot(a, b)
+	default:
+		return 1 - memory.CosineSimilarity(a, b)
+	}
+}
+
+// distance is Distance with the norms of a and b computed already, which
+// saves two of the three passes over the vectors for Cosine.
+func (m Metric) distance(a []float32, normA float64, b []float32, normB float64) float64 {
+	if m != Cosine {
+		return m.Distance(a, b)
+	}
+	if normA == 0 || normB == 0 {
+		return 1
+	}
+	return 1 - memory.Dot(a, b)/(normA*normB)
+}
+
+// Index is an HNSW graph of vectors. The graph parameters must be set before
+// the first Insert, EfSearch before the index is shared.
+//
+// Inserts and deletes are serialised, searches run concurrently with each
+// other. Deleted vectors stay in the graph to keep it connected, but are
+// never returned, until Compact rebuilds it.
+type Index struct {
+	// M is how many neighbours a node links to on each layer, twice as many
+	// on the bottom layer. Higher M improves recall for more memory.
+	M int
+	// EfConstruction is how many candidate neighbours an insert considers.
+	// Higher values build a better graph, more slowly.
+	EfConstruction int
+	// EfSearch is how many candidates a search considers, at least k.
+	// Higher values improve recall, more slowly.
+	EfSearch int
+	Metric   Metric
+
+	mu       sync.RWMutex
+	nodes    []*node
+	ids      map[string]int // vector ID -> node, only for vectors not deleted
+	entry    int            // node searches start from, -1 while empty
+	maxLevel int
+	dim      int
+	rng      *rand.Rand
+}
+
+type node struct {
+	id     string
+	vector []float32
+	norm   float64
+	// neighbors holds the linked nodes on each layer the node is on.
+	neighbors [][]int
+	deleted   bool
+}
+
+// Result is a vector near the query.
+type Result struct {
+	ID       string
+	Distance float64
+}
+
+func NewIndex() *Index {
+	return &Index{
+		M:              DefaultM,
+		EfConstruction: DefaultEfConstruction,
+		EfSearch:       DefaultEfSearch,
+		ids:            make(map[string]int),
+		entry:          -1,
+		rng:            rand.New(rand.NewPCG(1, 2)),
+	}
+}
+
+// Insert adds a vector under id, replacing the vector stored under it
+// before. All vectors must have the dimension of the first one.
+func (idx *Index) Insert(id string, vector []float32) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if idx.dim == 0 {
+		idx.dim = len(vector)
+	}
+	if len(vector) != idx.dim {
+		return fmt.Errorf("%w: got %d, want %d", ErrDimension, len(vector), idx.dim)
+	}
+	if old, exists := idx.ids[id]; exists {
+		idx.nodes[old].deleted = true
+	}
+	idx.insert(id, append([]float32(nil), vector...))
+	return nil
+}
+
+// insert links a new node for the vector into the graph.
+func (idx *Index) insert(id string, vector []float32) {
+	level := idx.randomLevel()
+	n := &node{id: id, vector: vector, norm: memory.Norm(vector), neighbors: make([][]int, level+1)}
+	nodeID := len(idx.nodes)
+	idx.nodes = append(idx.nodes, n)
+	idx.ids[id] = nodeID
+	if idx.entry < 0 {
+		idx.entry = nodeID
+		idx.maxLevel = level
+		return
+	}
+
+	q := query{vector: n.vector, norm: n.norm}
+	ep := candidate{node: idx.entry, distance: idx.distance(q, idx.entry)}
+	for l := idx.maxLevel; l > level; l-- {
+		ep = idx.greedy(q, ep, l)
+	}
+	for l := min(level, idx.maxLevel); l >= 0; l-- {
+		candidates := idx.searchLayer(q, ep, idx.EfConstruction, l, false)
+		n.neighbors[l] = idx.selectNeighbors(candidates, idx.maxLinks(l))
+		for _, neighbor := range n.neighbors[l] {
+			idx.link(neighbor, nodeID, l)
+		}
+		ep = candidates[0]
+	}
+	if level > idx.maxLevel {
+		idx.entry = nodeID
+		idx.maxLevel = level
+	}
+}
+
+// Delete removes the vector stored under id. Its node stays in the graph, so
+// that the nodes linked through it stay reachable, until Compact.
+func (idx *Index) Delete(id string) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	nodeID, exists := idx.ids[id]
+	if !exists {
+		return fmt.Errorf("%w: %q", ErrNotFound, id)
+	}
+	idx.nodes[nodeID].deleted = true
+	delete(idx.ids, id)
+	return nil
+}
+
+// Compact rebuilds the graph from the vectors that are not deleted, dropping
+// the nodes left by Delete and by Insert replacing a vector. Those nodes
+// still cost memory and search time, so indexes with many deletes should be
+// compacted now and then. It takes as long as inserting every vector again,
+// and searches wait for it.
+func (idx *Index) Compact() {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if len(idx.nodes) == len(idx.ids) {
+		return
+	}
+	nodes := idx.nodes
+	idx.nodes = make([]*node, 0, len(idx.ids))
+	idx.ids = make(map[string]int, len(idx.ids))
+	idx.entry = -1
+	idx.maxLevel = 0
+	for _, n := range nodes {
+		if !n.deleted {
+			idx.insert(n.id, n.vector)
+		}
+	}
+}
+
+// Len returns the number of vectors in the index.
+func (idx *Index) Len() int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return len(idx.ids)
+}
+
+// Search returns the k vectors nearest to vector, nearest first. Being
+// approximate, it can miss some of the true nearest vectors.
+func (idx *Index) Search(vector []float32, k int) ([]Result, error) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	if k <= 0 || len(idx.ids) == 0 {
+		return nil, nil
+	}
+	if len(vector) != idx.dim {
+		return nil, fmt.Errorf("%w: got %d, want %d", ErrDimension, len(vector), idx.dim)
+	}
+
+	q := query{vector: vector, norm: memory.Norm(vector)}
+	ep := candidate{node: idx.entry, distance: idx.distance(q, idx.entry)}
+	for l := idx.maxLevel; l > 0; l-- {
+		ep = idx.greedy(q, ep, l)
+	}
+	candidates := idx.searchLayer(q, ep, max(idx.EfSearch, k), 0, true)
+
+	results := make([]Result, 0, min(k, len(candidates)))
+	for _, c := range candidates[:min(k, len(candidates))] {
+		results = append(results, Result{ID: idx.nodes[c.node].id, Distance: c.distance})
+	}
+	return results, nil
+}
+
+// randomLevel draws the top layer of a new node, each layer holding about
+// 1/M of the nodes of the one below.
+func (idx *Index) randomLevel() int {
+	return int(-math.Log(1-idx.rng.Float64()) / math.Log(float64(max(idx.M, 2))))
+}
+
+// maxLinks is how many neighbours a node keeps on a layer.
+func (idx *Index) maxLinks(level int) int {
+	if level == 0 {
+		return 2 * idx.M
+	}
+	return idx.M
+}
+
+// query is a vector searched for and its norm.
+type query struct {
+	vector []float32
+	norm   float64
+}
+
+func (idx *Index) distance(q query, nodeID int) float64 {
+	n := idx.nodes[nodeID]
+	return idx.Metric.distance(q.vector, q.norm, n.vector, n.norm)
+}
+
+// greedy walks a layer to the node nearest to q, from ep.
+func (idx *Index) greedy(q query, ep candidate, level int) candidate {
+	for changed := true; changed; {
+		changed = false
+		for _, neighbor := range idx.nodes[ep.node].neighbors[level] {
+			if d := idx.distance(q, neighbor); d < ep.distance {
+				ep = candidate{node: neighbor, distance: d}
+				changed = true
+			}
+		}
+	}
+	return ep
+}
+
+// searchLayer returns up to ef nodes of a layer nearest to q, nearest first,
+// searching from ep. With liveOnly, deleted nodes are walked through but not
+// returned.
+func (idx *Index) searchLayer(q query, ep candidate, ef int, level int, liveOnly bool) []candidate {
+	visited := map[int]bool{ep.node: true}
+	candidates := &nearest{ep}
+	results := &furthest{}
+	if !liveOnly || !idx.nodes[ep.node].deleted {
+		heap.Push(results, ep)
+	}
+
+	for candidates.Len() > 0 {
+		c := heap.Pop(candidates).(candidate)
+		if results.Len() >= ef && c.distance > (*results)[0].distance {
+			break
+		}
+		for _, neighbor := range idx.nodes[c.node].neighbors[level] {
+			if visited[neighbor] {
+				continue
+			}
+			visited[neighbor] = true
+			d := idx.distance(q, neighbor)
+			if results.Len() >= ef && d >= (*results)[0].distance {
+				continue
+			}
+			heap.Push(candidates, candidate{node: neighbor, distance: d})
+			if liveOnly && idx.nodes[neighbor].deleted {
+				continue
+			}
+			heap.Push(results, candidate{node: neighbor, distance: d})
+			if results.Len() > ef {
+				heap.Pop(results)
+			}
+		}
+	}
+
+	sorted := []candidate(*results)
+	sort.Slice(sorted, func(a int, b int) bool {
+		return sorted[a].distance < sorted[b].distance
+	})
+	return sorted
+}
+
+// selectNeighbors picks up to m of the candidates, nearest first, skipping
+// those nearer to an already picked neighbour than to the new node so links
+// spread out in different directions. Skipped candidates fill the remaining
+// links, which keeps sparse regions connected.
+func (idx *Index) selectNeighbors(candidates []candidate, m int) []int {
+	selected := make([]int, 0, m)
+	var skipped []int
+	for _, c := range candidates {
+		if len(selected) >= m {
+			break
+		}
+		diverse := true
+		n := idx.nodes[c.node]
+		for _, s := range selected {
+			if idx.distance(query{vector: n.vector, norm: n.norm}, s) < c.distance {
+				diverse = false
+				break
+			}
+		}
+		if diverse {
+			selected = append(selected, c.node)
+		} else {
+			skipped = append(skipped, c.node)
+		}
+	}
+	for _, s := range skipped {
+		if len(selected) >= m {
+			break
+		}
+		selected = append(selected, s)
+	}
+	return selected
+}
+
+// link adds a link from nodeID to neighbor on a layer, pruning the links of
+// nodeID back to the nearest diverse ones when there are too many.
+func (idx *Index) link(nodeID int, neighbor int, level int) {
+	n := idx.nodes[nodeID]
+	n.neighbors[level] = append(n.neighbors[level], neighbor)
+	if len(n.neighbors[level]) <= idx.maxLinks(level) {
+		return
+	}
+
+	candidates := make([]candidate, len(n.neighbors[level]))
+	for i, other := range n.neighbors[level] {
+		candidates[i] = candidate{node: other, distance: idx.distance(query{vector: n.vector, norm: n.norm}, other)}
+	}
+	sort.Slice(candidates, func(a int, b int) bool {
+		return candidates[a].distance < candidates[b].distance
+	})
+	n.neighbors[level] = idx.selectNeighbors(candidates, idx.maxLinks(level))
+}
+
+// candidate is a node and its distance to the vector searched for.
+type candidate struct {
+	node     int
+	distance float64
+}
+
+// nearest is a min-heap of candidates.
+type nearest []candidate
+
+func (h nearest) Len() int           { return len(h) }
+func (h nearest) Less(a, b int) bool { return h[a].distance < h[b].distance }
+func (h nearest) Swap(a, b int)      { h[a], h[b] = h[b], h[a] }
+func (h *nearest) Push(x any)        { *h = append(*h, x.(candidate)) }
+func (h *nearest) Pop() any {
+	last := (*h)[len(*h)-1]
+	*h = (*h)[:len(*h)-1]
+	return last
+}
+
+// furthest is a max-heap of candidates.
+type furthest []candidate
+
+func (h furthest) Len() int           { return len(h) }
+func (h furthest) Less(a, b int) bool { return h[a].distance > h[b].distance }
+func (h furthest) Swap(a, b int)      { h[a], h[b] = h[b], h[a] }
+func (h *furthest) Push(x any)        { *h = append(*h, x.(candidate)) }
+func (h *furthest) Pop() any {
+	last := (*h)[len(*h)-1]
+	*h = (*h)[:len(*h)-1]
+	return last
+}
@@ -0,0 +1,196 @@
+package hnsw
+
+import (
+	"errors"
+	"math/rand/v2"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+// clusteredVectors returns n vectors drawn around a few random centers, like
+// embeddings of related texts.
+func clusteredVectors(rng *rand.Rand, n int, dim int, centers [][]float32) [][]float32 {
+	vectors := make([][]float32, n)
+	for i := range vectors {
+		vectors[i] = randomVector(rng, dim, centers[rng.IntN(len(centers))], 0.3)
+	}
+	return vectors
+}
+
+// randomVector returns center plus gaussian noise of the given spread.
+func randomVector(rng *rand.Rand, dim int, center []float32, spread float64) []float32 {
+	v := make([]float32, dim)
+	for i := range v {
+		v[i] = float32(rng.NormFloat64() * spread)
+		if center != nil {
+			v[i] += center[i]
+		}
+	}
+	return v
+}
+
+// corpus builds an index of n clustered vectors and queries drawn around the
+// same centers.
+func corpus(tb testing.TB, metric Metric, n int, dim int, queries int) (*Index, [][]float32, [][]float32) {
+	tb.Helper()
+	rng := rand.New(rand.NewPCG(1, 2))
+	centers := make([][]float32, 50)
+	for i := range centers {
+		centers[i] = randomVector(rng, dim, nil, 1)
+	}
+	vectors := clusteredVectors(rng, n, dim, centers)
+
+	index := NewIndex()
+	index.Metric = metric
+	for i, v := range vectors {
+		if err := index.Insert(strconv.Itoa(i), v); err != nil {
+			tb.Fatal(err)
+		}
+	}
+	return index, vectors, clusteredVectors(rng, queries, dim, centers)
+}
+
+// bruteForce returns the IDs of the k vectors nearest to q, skipping nil
+// vectors.
+func bruteForce(metric Metric, vectors [][]float32, q []float32, k int) map[string]bool {
+	type scored struct {
+		id       int
+		distance float64
+	}
+	all := make([]scored, 0, len(vectors))
+	for i, v := range vectors {
+		if v != nil {
+			all = append(all, scored{id: i, distance: metric.Distance(q, v)})
+		}
+	}
+	sort.Slice(all, func(a int, b int) bool {
+		return all[a].distance < all[b].distance
+	})
+	ids := make(map[string]bool, k)
+	for _, s := range all[:min(k, len(all))] {
+		ids[strconv.Itoa(s.id)] = true
+	}
+	return ids
+}
+
+// recall returns the share of the true k nearest neighbours the index finds.
+func recall(tb testing.TB, index *Index, vectors [][]float32, queries [][]float32, k int) float64 {
+	tb.Helper()
+	found := 0
+	for _, q := range queries {
+		truth := bruteForce(index.Metric, vectors, q, k)
+		results, err := index.Search(q, k)
+		if err != nil {
+			tb.Fatal(err)
+		}
+		for _, r := range results {
+			if truth[r.ID] {
+				found++
+			}
+		}
+	}
+	return float64(found) / float64(len(queries)*k)
+}
+
+func TestRecall(t *testing.T) {
+	n := 1_000
+	if testing.Short() {
+		n = 300
+	}
+	for _, metric := range []Metric{Cosine, L2, Dot} {
+		t.Run(metric.String(), func(t *testing.T) {
+			index, vectors, queries := corpus(t, metric, n, 32, 100)
+			if got := recall(t, index, vectors, queries, 10); got < 0.95 {
+				t.Errorf("got recall@10 %.4f, want at least 0.95", got)
+			}
+		})
+	}
+}
+
+func TestDeleteCompact(t *testing.T) {
+	index, vectors, queries := corpus(t, Cosine, 500, 32, 50)
+	live := append([][]float32(nil), vectors...)
+	for i := 0; i < len(vectors); i += 2 {
+		if err := index.Delete(strconv.Itoa(i)); err != nil {
+			t.Fatal(err)
+		}
+		live[i] = nil
+	}
+	// Replacing a vector leaves its old node behind too
+	for i := 1; i < 100; i += 2 {
+		if err := index.Insert(strconv.Itoa(i), vectors[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	check := func(t *testing.T) {
+		t.Helper()
+		for _, q := range queries {
+			results, err := index.Search(q, 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, r := range results {
+				if id, _ := strconv.Atoi(r.ID); live[id] == nil {
+					t.Fatalf("got deleted vector %s", r.ID)
+				}
+			}
+		}
+		if got := recall(t, index, live, queries, 10); got < 0.95 {
+			t.Errorf("got recall@10 %.4f, want at least 0.95", got)
+		}
+		if got := index.Len(); got != len(vectors)/2 {
+			t.Errorf("got length %d, want %d", got, len(vectors)/2)
+		}
+	}
+	check(t)
+	if got := len(index.nodes); got != len(vectors)+50 {
+		t.Fatalf("got %d nodes before compacting, want %d", got, len(vectors)+50)
+	}
+
+	index.Compact()
+	check(t)
+	if got := len(index.nodes); got != len(vectors)/2 {
+		t.Errorf("got %d nodes after compacting, want %d", got, len(vectors)/2)
+	}
+	if err := index.Delete("0"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("got error %v deleting a compacted vector, want %v", err, ErrNotFound)
+	}
+	if err := index.Insert("0", vectors[0]); err != nil {
+		t.Fatal(err)
+	}
+	if results, err := index.Search(vectors[0], 1); err != nil || len(results) != 1 || results[0].ID != "0" {
+		t.Errorf("got %v, %v searching for a vector inserted after compacting, want it", results, err)
+	}
+}
+
+var (
+	benchOnce    sync.Once
+	benchIndex   *Index
+	benchVectors [][]float32
+	benchQueries [][]float32
+)
+
+// BenchmarkSearch reports recall@10 against brute force next to the time per
+// search for each EfSearch.
+func BenchmarkSearch(b *testing.B) {
+	benchOnce.Do(func() {
+		benchIndex, benchVectors, benchQueries = corpus(b, Cosine, 20_000, 64, 200)
+	})
+
+	for _, ef := range []int{10, 50, 100, 200} {
+		b.Run("ef="+strconv.Itoa(ef), func(b *testing.B) {
+			benchIndex.EfSearch = ef
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := benchIndex.Search(benchQueries[i%len(benchQueries)], 10); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.StopTimer()
+			b.ReportMetric(recall(b, benchIndex, benchVectors, benchQueries, 10), "recall@10")
+		})
+	}
+}
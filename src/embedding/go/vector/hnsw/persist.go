@@ -0,0 +1,187 @@
+package hnsw
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/jacygao/ai/internal/indexfile"
+	"github.com/jacygao/ai/vector/memory"
+)
+
+// Index files are framed by the indexfile package with the magic
+// "HNSWIDX\x00". Vector components are little endian float32 bits. The body is
+//
+//	header    JSON encoded fileHeader: graph parameters, entry node and dimension
+//	nodes     count, then per node its ID, whether it is deleted, its vector,
+//	          its number of layers and per layer the linked nodes
+//
+// Deleted nodes are kept, the graph would lose links without them.
+const (
+	fileMagic   = "HNSWIDX\x00"
+	fileVersion = 1
+)
+
+// fileHeader holds the settings of a saved index.
+type fileHeader struct {
+	M              int    `json:"m"`
+	EfConstruction int    `json:"ef_construction"`
+	EfSearch       int    `json:"ef_search"`
+	Metric         string `json:"metric"`
+	Dim            int    `json:"dim"`
+	Entry          int    `json:"entry"`
+	MaxLevel       int    `json:"max_level"`
+}
+
+var (
+	ErrCorrupt = errors.New("hnsw: index file is corrupt")
+	ErrVersion = errors.New("hnsw: unsupported index file version")
+)
+
+// Save writes the index to path. The file is written next to it first and
+// renamed, so readers never see a partial index.
+func (idx *Index) Save(path string) error {
+	return indexfile.Save(path, idx)
+}
+
+// Load reads an index saved with Save.
+func Load(path string) (*Index, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error loading index: %w", err)
+	}
+	return decodeIndex(data)
+}
+
+// WriteTo encodes the index.
+func (idx *Index) WriteTo(w io.Writer) (int64, error) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	header, err := json.Marshal(fileHeader{
+		M:              idx.M,
+		EfConstruction: idx.EfConstruction,
+		EfSearch:       idx.EfSearch,
+		Metric:         idx.Metric.String(),
+		Dim:            idx.dim,
+		Entry:          idx.entry,
+		MaxLevel:       idx.maxLevel,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("error encoding index header: %w", err)
+	}
+
+	e := indexfile.NewEncoder(fileMagic, fileVersion)
+	e.Bytes(header)
+
+	e.Uvarint(len(idx.nodes))
+	for _, n := range idx.nodes {
+		e.Bytes([]byte(n.id))
+		if n.deleted {
+			e.Uvarint(1)
+		} else {
+			e.Uvarint(0)
+		}
+		e.Float32s(n.vector)
+		e.Uvarint(len(n.neighbors))
+		for _, neighbors := range n.neighbors {
+			e.Uvarint(len(neighbors))
+			for _, neighbor := range neighbors {
+				e.Uvarint(neighbor)
+			}
+		}
+	}
+
+	return e.WriteTo(w)
+}
+
+// ReadIndex decodes an index encoded with WriteTo.
+func ReadIndex(r io.Reader) (*Index, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("error reading index: %w", err)
+	}
+	return decodeIndex(data)
+}
+
+func decodeIndex(data []byte) (*Index, error) {
+	d, err := indexfile.NewDecoder(data, fileMagic, fileVersion)
+	var versionErr *indexfile.VersionError
+	if errors.As(err, &versionErr) {
+		return nil, fmt.Errorf("%w: %d", ErrVersion, versionErr.Version)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCorrupt, err)
+	}
+
+	var header fileHeader
+	if err := json.Unmarshal(d.Bytes(), &header); d.Err() == nil && err != nil {
+		return nil, fmt.Errorf("%w: header: %v", ErrCorrupt, err)
+	}
+	if d.Err() != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCorrupt, d.Err())
+	}
+
+	idx := NewIndex()
+	idx.M = header.M
+	idx.EfConstruction = header.EfConstruction
+	idx.EfSearch = header.EfSearch
+	metric, err := ParseMetric(header.Metric)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCorrupt, err)
+	}
+	idx.Metric = metric
+	idx.dim = header.Dim
+	idx.entry = header.Entry
+	idx.maxLevel = header.MaxLevel
+
+	count := d.Uvarint()
+	for nodeID := 0; nodeID < count && d.Err() == nil; nodeID++ {
+		n := &node{id: string(d.Bytes()), deleted: d.Uvarint() == 1}
+		n.vector = d.Float32s(idx.dim)
+		if d.Err() != nil {
+			break
+		}
+		n.norm = memory.Norm(n.vector)
+		levels := d.Uvarint()
+		if levels == 0 || levels > header.MaxLevel+1 {
+			d.Fail(fmt.Errorf("node %q has %d layers", n.id, levels))
+			break
+		}
+		n.neighbors = make([][]int, levels)
+		for l := 0; l < levels && d.Err() == nil; l++ {
+			links := d.Uvarint()
+			n.neighbors[l] = make([]int, 0, min(links, d.Len()))
+			for i := 0; i < links && d.Err() == nil; i++ {
+				neighbor := d.Uvarint()
+				if neighbor >= count {
+					d.Fail(fmt.Errorf("node %d out of range", neighbor))
+				}
+				n.neighbors[l] = append(n.neighbors[l], neighbor)
+			}
+		}
+		idx.nodes = append(idx.nodes, n)
+		if !n.deleted {
+			idx.ids[n.id] = nodeID
+		}
+	}
+	if d.Err() == nil && len(idx.nodes) > 0 && (idx.entry < 0 || idx.entry >= len(idx.nodes) || len(idx.nodes[idx.entry].neighbors) != idx.maxLevel+1) {
+		d.Fail(fmt.Errorf("invalid entry node %d", idx.entry))
+	}
+	// Searches follow links down layers, so linked nodes must be on the layer
+	for nodeID := 0; nodeID < len(idx.nodes) && d.Err() == nil; nodeID++ {
+		for l, neighbors := range idx.nodes[nodeID].neighbors {
+			for _, neighbor := range neighbors {
+				if len(idx.nodes[neighbor].neighbors) <= l {
+					d.Fail(fmt.Errorf("node %d links to node %d above its layers", nodeID, neighbor))
+				}
+			}
+		}
+	}
+	if err := d.Finish(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCorrupt, err)
+	}
+	return idx, nil
+}
@@ -0,0 +1,103 @@
+package hnsw
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSaveLoad(t *testing.T) {
+	index, _, queries := corpus(t, L2, 500, 16, 20)
+	index.EfSearch = 40
+	for _, id := range []string{"3", "42", "250"} {
+		if err := index.Delete(id); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "index.hnsw")
+	if err := index.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if loaded.EfSearch != index.EfSearch || loaded.Metric != index.Metric || loaded.Len() != index.Len() {
+		t.Errorf("got EfSearch=%d %s with %d vectors, want EfSearch=%d %s with %d vectors",
+			loaded.EfSearch, loaded.Metric, loaded.Len(), index.EfSearch, index.Metric, index.Len())
+	}
+	for _, q := range queries {
+		want, err := index.Search(q, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := loaded.Search(q, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	}
+
+	// The same graph encodes to the same bytes
+	var saved, resaved bytes.Buffer
+	if _, err := index.WriteTo(&saved); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loaded.WriteTo(&resaved); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(saved.Bytes(), resaved.Bytes()) {
+		t.Error("loaded index encodes differently")
+	}
+
+	if err := loaded.Insert("new", queries[0]); err != nil {
+		t.Fatal(err)
+	}
+	if results, err := loaded.Search(queries[0], 1); err != nil || len(results) != 1 || results[0].ID != "new" {
+		t.Errorf("got %v, %v searching for an inserted vector", results, err)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	index, _, _ := corpus(t, Cosine, 50, 8, 0)
+	var buf bytes.Buffer
+	if _, err := index.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	flipped := bytes.Clone(data)
+	flipped[len(flipped)/2] ^= 0xff
+	newer := bytes.Clone(data)
+	newer[len(fileMagic)]++
+
+	tests := []struct {
+		name string
+		data []byte
+		err  error
+	}{
+		{name: "empty", data: nil, err: ErrCorrupt},
+		{name: "other magic", data: append([]byte("BM25IDX\x00"), data[len(fileMagic):]...), err: ErrCorrupt},
+		{name: "other version", data: newer, err: ErrVersion},
+		{name: "flipped byte", data: flipped, err: ErrCorrupt},
+		{name: "truncated", data: data[:len(data)-10], err: ErrCorrupt},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "index.hnsw")
+			if err := os.WriteFile(path, test.data, 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := Load(path); !errors.Is(err, test.err) {
+				t.Errorf("got error %v, want %v", err, test.err)
+			}
+		})
+	}
+}
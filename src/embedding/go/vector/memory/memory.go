@@ -0,0 +1,170 @@
+// Package memory is an in-memory vector index searched exhaustively by
+// cosine similarity. It needs no server, which makes it the default for
+// small corpora and demos.
+package memory
+
+import (
+	"container/heap"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+)
+
+var (
+	ErrNotFound  = errors.New("memory: document not found")
+	ErrDimension = errors.New("memory: vector dimension mismatch")
+)
+
+// Index holds documents and their embeddings. It is safe for concurrent use.
+type Index struct {
+	mu   sync.RWMutex
+	docs map[string]*document
+	dim  int
+}
+
+type document struct {
+	text   string
+	vector []float32
+}
+
+// Result is a document near the query vector. Score is the cosine
+// similarity, higher is nearer.
+type Result struct {
+	ID    string
+	Text  string
+	Score float64
+}
+
+func NewIndex() *Index {
+	return &Index{docs: make(map[string]*document)}
+}
+
+// Add stores a document, replacing any document with the same id. All
+// vectors must have the dimension of the first one.
+func (idx *Index) Add(id string, text string, vector []float32) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if idx.dim == 0 {
+		idx.dim = len(vector)
+	}
+	if len(vector) != idx.dim {
+		return fmt.Errorf("%w: got %d, want %d", ErrDimension, len(vector), idx.dim)
+	}
+	idx.docs[id] = &document{text: text, vector: vector}
+	return nil
+}
+
+// Delete removes the document with id.
+func (idx *Index) Delete(id string) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if _, exists := idx.docs[id]; !exists {
+		return fmt.Errorf("%w: %q", ErrNotFound, id)
+	}
+	delete(idx.docs, id)
+	return nil
+}
+
+// Len returns the number of documents in the index.
+func (idx *Index) Len() int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return len(idx.docs)
+}
+
+// Search returns the k documents most similar to vector, best first. Ties
+// are broken by ID.
+func (idx *Index) Search(vector []float32, k int) ([]Result, error) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	if k <= 0 || len(idx.docs) == 0 {
+		return nil, nil
+	}
+	if len(vector) != idx.dim {
+		return nil, fmt.Errorf("%w: got %d, want %d", ErrDimension, len(vector), idx.dim)
+	}
+
+	top := &topK{k: k}
+	for id, doc := range idx.docs {
+		top.offer(Result{ID: id, Text: doc.text, Score: CosineSimilarity(vector, doc.vector)})
+	}
+	return top.sorted(), nil
+}
+
+// topK keeps the k best results seen, worst first, so a search holds k
+// results rather than the whole corpus.
+type topK struct {
+	k       int
+	results []Result
+}
+
+func (t *topK) Len() int           { return len(t.results) }
+func (t *topK) Less(a, b int) bool { return worse(t.results[a], t.results[b]) }
+func (t *topK) Swap(a, b int)      { t.results[a], t.results[b] = t.results[b], t.results[a] }
+func (t *topK) Push(x any)         { t.results = append(t.results, x.(Result)) }
+func (t *topK) Pop() any {
+	last := t.results[len(t.results)-1]
+	t.results = t.results[:len(t.results)-1]
+	return last
+}
+
+func (t *topK) offer(r Result) {
+	if len(t.results) < t.k {
+		heap.Push(t, r)
+	} else if worse(t.results[0], r) {
+		t.results[0] = r
+		heap.Fix(t, 0)
+	}
+}
+
+// sorted returns the results best first.
+func (t *topK) sorted() []Result {
+	results := make([]Result, len(t.results))
+	for i := len(results) - 1; i >= 0; i-- {
+		results[i] = heap.Pop(t).(Result)
+	}
+	return results
+}
+
+// worse reports whether a ranks below b: a lower score, or an equal score
+// and a later ID.
+func worse(a Result, b Result) bool {
+	if a.Score != b.Score {
+		return a.Score < b.Score
+	}
+	return a.ID > b.ID
+}
+
+// CosineSimilarity returns the cosine of the angle between two vectors, 0
+// if their lengths differ or either is all zeros.
+func CosineSimilarity(vec1, vec2 []float32) float64 {
+	if len(vec1) != len(vec2) {
+		return 0
+	}
+
+	magnitude := Norm(vec1) * Norm(vec2)
+	if magnitude == 0 {
+		return 0 // Avoid division by zero
+	}
+
+	return Dot(vec1, vec2) / magnitude
+}
+
+// Dot returns the dot product of two vectors of the same length.
+func Dot(vec1, vec2 []float32) float64 {
+	var dotProduct float64
+	for i := range vec1 {
+		// Multiplying in float32 would lose precision and overflow
+		dotProduct += float64(vec1[i]) * float64(vec2[i])
+	}
+	return dotProduct
+}
+
+// Norm returns the Euclidean length of a vector.
+func Norm(vec []float32) float64 {
+	return math.Sqrt(Dot(vec, vec))
+}
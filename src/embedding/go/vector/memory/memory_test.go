@@ -0,0 +1,81 @@
+package memory
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"sort"
+	"testing"
+)
+
+func TestSearch(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	index := NewIndex()
+	var all []Result
+	for i := 0; i < 500; i++ {
+		id := fmt.Sprintf("doc%03d", i)
+		vector := []float32{float32(rng.NormFloat64()), float32(rng.NormFloat64()), float32(rng.NormFloat64())}
+		// Some documents share a direction, so scores tie
+		if i%50 == 0 {
+			vector = []float32{1, 1, 0}
+		}
+		if err := index.Add(id, "text of "+id, vector); err != nil {
+			t.Fatal(err)
+		}
+		all = append(all, Result{ID: id, Text: "text of " + id, Score: CosineSimilarity([]float32{2, 2, 0}, vector)})
+	}
+	sort.Slice(all, func(a int, b int) bool {
+		if all[a].Score != all[b].Score {
+			return all[a].Score > all[b].Score
+		}
+		return all[a].ID < all[b].ID
+	})
+
+	for _, k := range []int{1, 5, 10, 499, 500, 1000} {
+		results, err := index.Search([]float32{2, 2, 0}, k)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := all[:min(k, len(all))]
+		if len(results) != len(want) {
+			t.Fatalf("k=%d: got %d results, want %d", k, len(results), len(want))
+		}
+		for i := range want {
+			if results[i] != want[i] {
+				t.Errorf("k=%d: got %+v at rank %d, want %+v", k, results[i], i+1, want[i])
+			}
+		}
+	}
+
+	if results, err := index.Search([]float32{1, 0, 0}, 0); results != nil || err != nil {
+		t.Errorf("got %v, %v for k=0, want nothing", results, err)
+	}
+	if _, err := index.Search([]float32{1, 0}, 5); !errors.Is(err, ErrDimension) {
+		t.Errorf("got error %v, want %v", err, ErrDimension)
+	}
+}
+
+func TestDot(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b []float32
+		want float64
+	}{
+		{name: "plain", a: []float32{1, 2, 3}, b: []float32{4, -5, 6}, want: 12},
+		// The products overflow, underflow or do not fit in float32
+		{name: "large", a: []float32{0x1p70, 0x1p70}, b: []float32{0x1p70, 0x1p70}, want: 0x1p141},
+		{name: "small", a: []float32{0x1p-100}, b: []float32{0x1p-100}, want: 0x1p-200},
+		{name: "precise", a: []float32{16777215}, b: []float32{3}, want: 50331645},
+	}
+	for _, test := range tests {
+		got := Dot(test.a, test.b)
+		if got != test.want {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+		}
+	}
+
+	if got := CosineSimilarity([]float32{3e20, 4e20}, []float32{3e20, 4e20}); math.Abs(got-1) > 1e-9 {
+		t.Errorf("got cosine similarity %v of large vectors, want 1", got)
+	}
+}
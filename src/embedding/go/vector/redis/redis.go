@@ -2,85 +2,301 @@ package redis
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"math"
+	"strconv"
+	"strings"
+	"unicode"
 
 	"github.com/redis/go-redis/v9"
 )
 
+var ErrSchemaMismatch = errors.New("redis: existing index does not match the schema")
+
+// FieldType is the type of a metadata field, which decides how it can be
+// filtered on.
+type FieldType string
+
+const (
+	// TagField matches exact values, @field:{a | b}.
+	TagField FieldType = "TAG"
+	// NumericField matches ranges, @field:[min max].
+	NumericField FieldType = "NUMERIC"
+	// TextField matches words, @field:(word).
+	TextField FieldType = "TEXT"
+)
+
+// Field declares a metadata field in the index schema. Only declared fields
+// can be filtered on and are returned by SearchVector.
+type Field struct {
+	Name string
+	Type FieldType
+}
+
+// Options configure the connection and the schema of the vector index.
+type Options struct {
+	Addr     string
+	Username string
+	Password string
+	DB       int
+	// TLSConfig enables TLS when set.
+	TLSConfig *tls.Config
+
+	IndexName string
+	// Prefix is prepended to the keys of stored documents, the index covers
+	// the hashes under it.
+	Prefix string
+	// Dim is the number of dimensions of the embeddings.
+	Dim int
+	// DistanceMetric is COSINE, L2 or IP.
+	DistanceMetric string
+	// Algorithm is HNSW, approximate, or FLAT, exact.
+	Algorithm string
+	// M, EFConstruction and EFRuntime tune HNSW indexes. Zero keeps the
+	// Redis defaults.
+	M              int
+	EFConstruction int
+	EFRuntime      int
+
+	// Fields are the metadata fields of the documents.
+	Fields []Field
+}
+
+// DefaultOptions connect to a local redis-stack without auth and index
+// 384 dimensional embeddings by cosine distance with HNSW.
+func DefaultOptions() Options {
+	return Options{
+		Addr:           "localhost:6379",
+		IndexName:      "vector_idx",
+		Prefix:         "docs:",
+		Dim:            384,
+		DistanceMetric: "COSINE",
+		Algorithm:      "HNSW",
+	}
+}
+
 type RedisClient struct {
 	client *redis.Client
+	opts   Options
 }
 
-func NewRedisClient() *RedisClient {
+// NewRedisClient connects to Redis and creates the vector index unless it
+// exists. An existing index is kept with its documents, but must match the
+// schema in opts.
+func NewRedisClient(ctx context.Context, opts Options) (*RedisClient, error) {
 	rdb := redis.NewClient(&redis.Options{
-		Addr:     "localhost:6379",
-		Password: "", // no password docs
-		DB:       0,  // use default DB
-		Protocol: 2,
+		Addr:      opts.Addr,
+		Username:  opts.Username,
+		Password:  opts.Password,
+		DB:        opts.DB,
+		TLSConfig: opts.TLSConfig,
+		Protocol:  2,
 	})
+	client := &RedisClient{client: rdb, opts: opts}
 
-	ctx := context.Background()
-	rdb.FTDropIndexWithArgs(ctx,
-		"vector_idx",
-		&redis.FTDropIndexOptions{
-			DeleteDocs: true,
-		},
-	)
-	_, err := rdb.FTCreate(ctx,
-		"vector_idx",
+	info, err := client.indexInfo(ctx)
+	if err == nil {
+		err = client.checkSchema(info)
+	} else if isUnknownIndex(err) {
+		err = client.createIndex(ctx)
+	}
+	if err != nil {
+		rdb.Close()
+		return nil, err
+	}
+	return client, nil
+}
+
+// Close closes the connection.
+func (rdb *RedisClient) Close() error {
+	return rdb.client.Close()
+}
+
+// DistanceMetric returns the metric of the index, COSINE, L2 or IP.
+func (rdb *RedisClient) DistanceMetric() string {
+	return strings.ToUpper(rdb.opts.DistanceMetric)
+}
+
+func (rdb *RedisClient) createIndex(ctx context.Context) error {
+	schema := []*redis.FieldSchema{{
+		FieldName: "content",
+		FieldType: redis.SearchFieldTypeText,
+	}}
+	for _, field := range rdb.opts.Fields {
+		fieldType, err := searchFieldType(field)
+		if err != nil {
+			return err
+		}
+		schema = append(schema, &redis.FieldSchema{FieldName: field.Name, FieldType: fieldType})
+	}
+
+	vectorArgs := &redis.FTVectorArgs{}
+	switch strings.ToUpper(rdb.opts.Algorithm) {
+	case "HNSW":
+		vectorArgs.HNSWOptions = &redis.FTHNSWOptions{
+			Dim:                    rdb.opts.Dim,
+			DistanceMetric:         rdb.opts.DistanceMetric,
+			Type:                   "FLOAT32",
+			MaxEdgesPerNode:        rdb.opts.M,
+			MaxAllowedEdgesPerNode: rdb.opts.EFConstruction,
+			EFRunTime:              rdb.opts.EFRuntime,
+		}
+	case "FLAT":
+		vectorArgs.FlatOptions = &redis.FTFlatOptions{
+			Dim:            rdb.opts.Dim,
+			DistanceMetric: rdb.opts.DistanceMetric,
+			Type:           "FLOAT32",
+		}
+	default:
+		return fmt.Errorf("redis: unknown vector algorithm %q, want HNSW or FLAT", rdb.opts.Algorithm)
+	}
+
+	schema = append(schema, &redis.FieldSchema{
+		FieldName:  "embedding",
+		FieldType:  redis.SearchFieldTypeVector,
+		VectorArgs: vectorArgs,
+	})
+
+	_, err := rdb.client.FTCreate(ctx,
+		rdb.opts.IndexName,
 		&redis.FTCreateOptions{
 			OnHash: true,
-			Prefix: []any{"docs:"},
-		},
-		&redis.FieldSchema{
-			FieldName: "content",
-			FieldType: redis.SearchFieldTypeText,
-		},
-		&redis.FieldSchema{
-			FieldName: "embedding",
-			FieldType: redis.SearchFieldTypeVector,
-			VectorArgs: &redis.FTVectorArgs{
-				HNSWOptions: &redis.FTHNSWOptions{
-					Dim:            384,
-					DistanceMetric: "COSINE",
-					Type:           "FLOAT32",
-				},
-			},
+			Prefix: []any{rdb.opts.Prefix},
 		},
+		schema...,
 	).Result()
+	if err != nil {
+		return fmt.Errorf("error creating index %s: %w", rdb.opts.IndexName, err)
+	}
+	return nil
+}
 
+func searchFieldType(field Field) (redis.SearchFieldType, error) {
+	if field.Name == "content" || field.Name == "embedding" || field.Name == "vector_distance" {
+		return redis.SearchFieldTypeInvalid, fmt.Errorf("redis: metadata field name %q is reserved", field.Name)
+	}
+	switch field.Type {
+	case TagField:
+		return redis.SearchFieldTypeTag, nil
+	case NumericField:
+		return redis.SearchFieldTypeNumeric, nil
+	case TextField:
+		return redis.SearchFieldTypeText, nil
+	}
+	return redis.SearchFieldTypeInvalid, fmt.Errorf("redis: unknown type %q of field %s, want TAG, NUMERIC or TEXT", field.Type, field.Name)
+}
+
+// indexInfo returns the FT.INFO reply of the index with its keys lower
+// cased. It is read raw, as the parsed reply leaves out vector fields.
+func (rdb *RedisClient) indexInfo(ctx context.Context) (map[string]any, error) {
+	reply, err := rdb.client.Do(ctx, "FT.INFO", rdb.opts.IndexName).Result()
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
+	return pairs(reply), nil
+}
 
-	return &RedisClient{
-		client: rdb,
+// checkSchema compares an existing index with the options. Vector settings
+// older Redis versions leave out of FT.INFO are not checked.
+func (rdb *RedisClient) checkSchema(info map[string]any) error {
+	var problems []string
+
+	definition := pairs(info["index_definition"])
+	if keyType := fmt.Sprint(definition["key_type"]); !strings.EqualFold(keyType, "HASH") {
+		problems = append(problems, fmt.Sprintf("indexes %s keys, want HASH", keyType))
+	}
+	prefixes, _ := definition["prefixes"].([]any)
+	hasPrefix := false
+	for _, prefix := range prefixes {
+		hasPrefix = hasPrefix || fmt.Sprint(prefix) == rdb.opts.Prefix
+	}
+	if !hasPrefix {
+		problems = append(problems, fmt.Sprintf("prefixes %v do not include %q", prefixes, rdb.opts.Prefix))
+	}
+
+	fields := make(map[string]map[string]any)
+	attributes, _ := info["attributes"].([]any)
+	for _, attribute := range attributes {
+		field := pairs(attribute)
+		fields[fmt.Sprint(field["attribute"])] = field
+	}
+	if content, ok := fields["content"]; !ok || !strings.EqualFold(fmt.Sprint(content["type"]), "TEXT") {
+		problems = append(problems, "content is not a TEXT field")
+	}
+	embedding, ok := fields["embedding"]
+	if !ok || !strings.EqualFold(fmt.Sprint(embedding["type"]), "VECTOR") {
+		problems = append(problems, "embedding is not a VECTOR field")
+	} else {
+		want := map[string]string{
+			"algorithm":       rdb.opts.Algorithm,
+			"data_type":       "FLOAT32",
+			"dim":             strconv.Itoa(rdb.opts.Dim),
+			"distance_metric": rdb.opts.DistanceMetric,
+		}
+		for _, key := range []string{"algorithm", "data_type", "dim", "distance_metric"} {
+			got, ok := embedding[key]
+			if ok && !strings.EqualFold(fmt.Sprint(got), want[key]) {
+				problems = append(problems, fmt.Sprintf("embedding %s is %v, want %s", key, got, want[key]))
+			}
+		}
+	}
+
+	for _, field := range rdb.opts.Fields {
+		existing, ok := fields[field.Name]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("field %s is missing", field.Name))
+		} else if got := fmt.Sprint(existing["type"]); !strings.EqualFold(got, string(field.Type)) {
+			problems = append(problems, fmt.Sprintf("field %s is %s, want %s", field.Name, got, field.Type))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s: %s", ErrSchemaMismatch, rdb.opts.IndexName, strings.Join(problems, "; "))
 	}
+	return nil
 }
 
-func (rdb *RedisClient) Set(key string, content string, embedding []float32) {
-	ctx := context.Background()
-	// Convert embedding to byte array
-	byteEmbedding := floatsToBytes(embedding)
-	fmt.Println(content)
+// pairs turns a RESP2 array of alternating keys and values, or a RESP3
+// map, into a map with lower cased keys.
+func pairs(reply any) map[string]any {
+	m := make(map[string]any)
+	switch reply := reply.(type) {
+	case []any:
+		for i := 0; i+1 < len(reply); i += 2 {
+			m[strings.ToLower(fmt.Sprint(reply[i]))] = reply[i+1]
+		}
+	case map[any]any:
+		for k, v := range reply {
+			m[strings.ToLower(fmt.Sprint(k))] = v
+		}
+	}
+	return m
+}
 
-	// Store in Redis
-	_, err := rdb.client.HSet(
-		ctx,
-		fmt.Sprintf("docs:%v", key),
-		map[string]any{
-			"content":   content,
-			"embedding": byteEmbedding,
-		}).Result()
+// isUnknownIndex reports whether err is Redis saying the index does not exist.
+func isUnknownIndex(err error) bool {
+	message := strings.ToLower(err.Error())
+	return strings.Contains(message, "unknown index name") || strings.Contains(message, "no such index")
+}
 
-	if err != nil {
-		fmt.Println("Error storing embedding:", err)
-		return
+// Set stores a document, its embedding and its metadata under key.
+// Numeric fields are stored as their decimal text.
+func (rdb *RedisClient) Set(ctx context.Context, key string, content string, embedding []float32, metadata map[string]string) error {
+	values := make(map[string]any, len(metadata)+2)
+	for name, value := range metadata {
+		values[name] = value
 	}
+	values["content"] = content
+	values["embedding"] = floatsToBytes(embedding)
 
-	fmt.Println("Embedding stored successfully!")
+	_, err := rdb.client.HSet(ctx, rdb.opts.Prefix+key, values).Result()
+	if err != nil {
+		return fmt.Errorf("error storing embedding: %w", err)
+	}
+	return nil
 }
 
 func floatsToBytes(fs []float32) []byte {
@@ -94,20 +310,44 @@ func floatsToBytes(fs []float32) []byte {
 	return buf
 }
 
-func (rdb *RedisClient) SearchVector(ctx context.Context, queryVector []float32) []string {
+// SearchResult is a document found by SearchVector. Distance is the
+// distance to the query vector in the metric of the index, lower is nearer.
+// Metadata holds the declared fields the document has.
+type SearchResult struct {
+	Key      string
+	Content  string
+	Metadata map[string]string
+	Distance float64
+}
+
+// SearchVector returns the k documents nearest to queryVector, nearest first.
+// A non-empty filter restricts the search to documents matching it, in the
+// Redis query syntax, like "@category:{ai} @year:[2020 2024]". TagFilter and
+// RangeFilter build filters from untrusted values.
+func (rdb *RedisClient) SearchVector(ctx context.Context, queryVector []float32, k int, filter string) ([]SearchResult, error) {
 	// Convert query vector to binary
 	queryBytes := floatsToBytes(queryVector)
-	// fmt.Println(queryBytes)
+
+	prefilter := "*"
+	if strings.TrimSpace(filter) != "" {
+		prefilter = "(" + filter + ")"
+	}
+	returns := []redis.FTSearchReturn{
+		{FieldName: "vector_distance"},
+		{FieldName: "content"},
+	}
+	for _, field := range rdb.opts.Fields {
+		returns = append(returns, redis.FTSearchReturn{FieldName: field.Name})
+	}
+
 	// Execute Redis search query
 	results, err := rdb.client.FTSearchWithArgs(
 		ctx,
-		"vector_idx",
-		"*=>[KNN 5 @embedding $vec AS vector_distance]",
+		rdb.opts.IndexName,
+		fmt.Sprintf("%s=>[KNN %d @embedding $vec AS vector_distance]", prefilter, k),
 		&redis.FTSearchOptions{
-			Return: []redis.FTSearchReturn{
-				{FieldName: "vector_distance"},
-				{FieldName: "content"},
-			},
+			Return:         returns,
+			SortBy:         []redis.FTSearchSortBy{{FieldName: "vector_distance", Asc: true}},
 			DialectVersion: 2,
 			Params: map[string]any{
 				"vec": queryBytes,
@@ -115,14 +355,69 @@ func (rdb *RedisClient) SearchVector(ctx context.Context, queryVector []float32)
 		}).Result()
 
 	if err != nil {
-		fmt.Println("Error running search:", err)
-		return nil
+		return nil, fmt.Errorf("error running search: %w", err)
 	}
 
-	found := []string{}
-
+	found := make([]SearchResult, 0, len(results.Docs))
 	for _, doc := range results.Docs {
-		found = append(found, doc.Fields["content"])
+		distance, err := strconv.ParseFloat(doc.Fields["vector_distance"], 64)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing distance of %s: %w", doc.ID, err)
+		}
+		var metadata map[string]string
+		for _, field := range rdb.opts.Fields {
+			if value, ok := doc.Fields[field.Name]; ok {
+				if metadata == nil {
+					metadata = make(map[string]string)
+				}
+				metadata[field.Name] = value
+			}
+		}
+		found = append(found, SearchResult{
+			Key:      strings.TrimPrefix(doc.ID, rdb.opts.Prefix),
+			Content:  doc.Fields["content"],
+			Metadata: metadata,
+			Distance: distance,
+		})
+	}
+	return found, nil
+}
+
+// TagFilter matches documents whose tag field has any of the values. The
+// values are escaped, so it is safe for tenant IDs and other input.
+func TagFilter(field string, values ...string) string {
+	escaped := make([]string, len(values))
+	for i, value := range values {
+		escaped[i] = escapeTag(value)
+	}
+	return fmt.Sprintf("@%s:{%s}", field, strings.Join(escaped, " | "))
+}
+
+// RangeFilter matches documents whose numeric field is between min and max
+// inclusive. Infinities leave that end open.
+func RangeFilter(field string, min float64, max float64) string {
+	return fmt.Sprintf("@%s:[%s %s]", field, formatBound(min), formatBound(max))
+}
+
+// escapeTag backslash escapes everything but letters, digits and
+// underscores, which Redis would otherwise read as query syntax.
+func escapeTag(value string) string {
+	var b strings.Builder
+	for _, r := range value {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func formatBound(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "+inf"
+	case math.IsInf(f, -1):
+		return "-inf"
 	}
-	return found
+	return strconv.FormatFloat(f, 'g', -1, 64)
 }
@@ -0,0 +1,226 @@
+package redis
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+)
+
+// attribute builds an FT.INFO attribute reply from alternating keys and
+// values.
+func attribute(keyValues ...any) []any {
+	return keyValues
+}
+
+// ftInfo builds an FT.INFO reply, in RESP2 form, of an index on keyType keys
+// under prefixes with the attributes.
+func ftInfo(keyType string, prefixes []any, attributes ...[]any) []any {
+	fields := make([]any, len(attributes))
+	for i, a := range attributes {
+		fields[i] = a
+	}
+	return []any{
+		"index_name", "vector_idx",
+		"index_definition", []any{"key_type", keyType, "prefixes", prefixes, "default_score", "1"},
+		"attributes", fields,
+		"num_docs", int64(0),
+	}
+}
+
+var (
+	contentAttribute   = attribute("identifier", "content", "attribute", "content", "type", "TEXT", "WEIGHT", "1")
+	embeddingAttribute = attribute("identifier", "embedding", "attribute", "embedding", "type", "VECTOR",
+		"algorithm", "HNSW", "data_type", "FLOAT32", "dim", int64(384), "distance_metric", "COSINE")
+	categoryAttribute = attribute("identifier", "category", "attribute", "category", "type", "TAG", "SEPARATOR", ",")
+	yearAttribute     = attribute("identifier", "year", "attribute", "year", "type", "NUMERIC")
+)
+
+func TestCheckSchema(t *testing.T) {
+	opts := DefaultOptions()
+	opts.Fields = []Field{{Name: "category", Type: TagField}, {Name: "year", Type: NumericField}}
+	client := &RedisClient{opts: opts}
+	prefixes := []any{"docs:"}
+
+	tests := []struct {
+		name string
+		info any
+		want string
+	}{
+		{
+			name: "matching",
+			info: ftInfo("HASH", prefixes, contentAttribute, embeddingAttribute, categoryAttribute, yearAttribute),
+		},
+		{
+			name: "matching in RESP3 and other cases",
+			info: map[any]any{
+				"Index_Definition": map[any]any{"Key_Type": "hash", "Prefixes": []any{"other:", "docs:"}},
+				"Attributes": []any{
+					map[any]any{"attribute": "content", "type": "text"},
+					map[any]any{"attribute": "embedding", "type": "vector", "algorithm": "hnsw", "data_type": "float32", "dim": "384", "distance_metric": "cosine"},
+					map[any]any{"attribute": "category", "type": "tag"},
+					map[any]any{"attribute": "year", "type": "numeric"},
+				},
+			},
+		},
+		{
+			name: "vector settings left out by older versions",
+			info: ftInfo("HASH", prefixes, contentAttribute, attribute("attribute", "embedding", "type", "VECTOR"), categoryAttribute, yearAttribute),
+		},
+		{
+			name: "extra fields",
+			info: ftInfo("HASH", prefixes, contentAttribute, embeddingAttribute, categoryAttribute, yearAttribute, attribute("attribute", "author", "type", "TEXT")),
+		},
+		{
+			name: "json keys",
+			info: ftInfo("JSON", prefixes, contentAttribute, embeddingAttribute, categoryAttribute, yearAttribute),
+			want: "indexes JSON keys, want HASH",
+		},
+		{
+			name: "other prefix",
+			info: ftInfo("HASH", []any{"other:"}, contentAttribute, embeddingAttribute, categoryAttribute, yearAttribute),
+			want: `prefixes [other:] do not include "docs:"`,
+		},
+		{
+			name: "no content",
+			info: ftInfo("HASH", prefixes, embeddingAttribute, categoryAttribute, yearAttribute),
+			want: "content is not a TEXT field",
+		},
+		{
+			name: "content of another type",
+			info: ftInfo("HASH", prefixes, attribute("attribute", "content", "type", "TAG"), embeddingAttribute, categoryAttribute, yearAttribute),
+			want: "content is not a TEXT field",
+		},
+		{
+			name: "no embedding",
+			info: ftInfo("HASH", prefixes, contentAttribute, categoryAttribute, yearAttribute),
+			want: "embedding is not a VECTOR field",
+		},
+		{
+			name: "other dimension",
+			info: ftInfo("HASH", prefixes, contentAttribute, attribute("attribute", "embedding", "type", "VECTOR", "dim", int64(768)), categoryAttribute, yearAttribute),
+			want: "embedding dim is 768, want 384",
+		},
+		{
+			name: "other algorithm and metric",
+			info: ftInfo("HASH", prefixes, contentAttribute, attribute("attribute", "embedding", "type", "VECTOR", "algorithm", "FLAT", "distance_metric", "L2"), categoryAttribute, yearAttribute),
+			want: "embedding algorithm is FLAT, want HNSW; embedding distance_metric is L2, want COSINE",
+		},
+		{
+			name: "other data type",
+			info: ftInfo("HASH", prefixes, contentAttribute, attribute("attribute", "embedding", "type", "VECTOR", "data_type", "FLOAT64"), categoryAttribute, yearAttribute),
+			want: "embedding data_type is FLOAT64, want FLOAT32",
+		},
+		{
+			name: "missing field",
+			info: ftInfo("HASH", prefixes, contentAttribute, embeddingAttribute, categoryAttribute),
+			want: "field year is missing",
+		},
+		{
+			name: "field of another type",
+			info: ftInfo("HASH", prefixes, contentAttribute, embeddingAttribute, attribute("attribute", "category", "type", "TEXT"), yearAttribute),
+			want: "field category is TEXT, want TAG",
+		},
+		{
+			name: "several problems",
+			info: ftInfo("JSON", prefixes, contentAttribute, embeddingAttribute),
+			want: "indexes JSON keys, want HASH; field category is missing; field year is missing",
+		},
+		{
+			name: "empty reply",
+			info: nil,
+			want: `indexes <nil> keys, want HASH; prefixes [] do not include "docs:"; content is not a TEXT field; embedding is not a VECTOR field; field category is missing; field year is missing`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := client.checkSchema(pairs(test.info))
+			if test.want == "" {
+				if err != nil {
+					t.Errorf("got error %v, want none", err)
+				}
+				return
+			}
+			want := "redis: existing index does not match the schema: vector_idx: " + test.want
+			if !errors.Is(err, ErrSchemaMismatch) || err.Error() != want {
+				t.Errorf("got error %v, want %s", err, want)
+			}
+		})
+	}
+}
+
+func TestPairs(t *testing.T) {
+	tests := []struct {
+		name  string
+		reply any
+		want  map[string]any
+	}{
+		{
+			name:  "resp2 array",
+			reply: []any{"Key_Type", "HASH", "prefixes", []any{"docs:"}},
+			want:  map[string]any{"key_type": "HASH", "prefixes": []any{"docs:"}},
+		},
+		{
+			name:  "resp2 array of odd length",
+			reply: []any{"dim", int64(384), "dangling"},
+			want:  map[string]any{"dim": int64(384)},
+		},
+		{
+			name:  "resp3 map",
+			reply: map[any]any{"Type": "VECTOR", "DIM": int64(384)},
+			want:  map[string]any{"type": "VECTOR", "dim": int64(384)},
+		},
+		{name: "nil", reply: nil, want: map[string]any{}},
+		{name: "string", reply: "OK", want: map[string]any{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := pairs(test.reply); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestTagFilter(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []string
+		want   string
+	}{
+		{name: "plain", values: []string{"ai"}, want: `@category:{ai}`},
+		{name: "any of several", values: []string{"ai", "ml"}, want: `@category:{ai | ml}`},
+		{name: "letters digits and underscores", values: []string{"tenant_42", "ünïcödé"}, want: `@category:{tenant_42 | ünïcödé}`},
+		{name: "hyphens and dots", values: []string{"tenant-1", "example.com"}, want: `@category:{tenant\-1 | example\.com}`},
+		{name: "spaces", values: []string{"machine learning"}, want: `@category:{machine\ learning}`},
+		{name: "separators and braces", values: []string{"a|b", "}{", "a,b"}, want: `@category:{a\|b | \}\{ | a\,b}`},
+		{name: "query syntax", values: []string{"@year:[0 9]", "-(x)", "*", "$param"}, want: `@category:{\@year\:\[0\ 9\] | \-\(x\) | \* | \$param}`},
+		{name: "quotes and backslashes", values: []string{`"x"`, `a\b`, "it's"}, want: `@category:{\"x\" | a\\b | it\'s}`},
+		{name: "other symbols", values: []string{"a—b", "x😀"}, want: `@category:{a\—b | x\😀}`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := TagFilter("category", test.values...); got != test.want {
+				t.Errorf("got %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestRangeFilter(t *testing.T) {
+	tests := []struct {
+		min, max float64
+		want     string
+	}{
+		{2020, 2024, "@year:[2020 2024]"},
+		{-1.5, 0.25, "@year:[-1.5 0.25]"},
+		{1e21, 1e-7, "@year:[1e+21 1e-07]"},
+		{math.Inf(-1), 2024, "@year:[-inf 2024]"},
+		{2020, math.Inf(1), "@year:[2020 +inf]"},
+	}
+	for _, test := range tests {
+		if got := RangeFilter("year", test.min, test.max); got != test.want {
+			t.Errorf("RangeFilter(year, %v, %v): got %s, want %s", test.min, test.max, got, test.want)
+		}
+	}
+}
@@ -4,10 +4,11 @@ import (
 	"flag"
 	"fmt"
 	"log"
-	"math"
 	"os"
 	"strconv"
 	"strings"
+
+	"github.com/jacygao/ai/vector/memory"
 )
 
 func parseVector(s string) ([]float32, error) {
@@ -49,25 +50,6 @@ func main() {
 		log.Fatalf("Error parsing v2: %v", err)
 	}
 
-	cos := CosineSimilarity(v1, v2)
+	cos := memory.CosineSimilarity(v1, v2)
 	fmt.Printf("Cosine similarity: %f\n", cos)
 }
-
-func CosineSimilarity(vec1, vec2 []float32) float64 {
-	if len(vec1) != len(vec2) {
-		return 0 // Return 0 if the vectors have different lengths
-	}
-
-	var dotProduct, magnitude1, magnitude2 float64
-	for i := range vec1 {
-		dotProduct += float64(vec1[i] * vec2[i])
-		magnitude1 += float64(vec1[i] * vec1[i])
-		magnitude2 += float64(vec2[i] * vec2[i])
-	}
-
-	if magnitude1 == 0 || magnitude2 == 0 {
-		return 0 // Avoid division by zero
-	}
-
-	return dotProduct / (math.Sqrt(magnitude1) * math.Sqrt(magnitude2))
-}